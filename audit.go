@@ -0,0 +1,355 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrAuditChain is returned by VerifyAudit for a broken hash chain.
+var ErrAuditChain = errors.New("Audit hash chain broken.")
+
+// AuditRecord records a Request executed on a Node.
+type AuditRecord struct {
+	Operator    string            `json:"operator"`
+	Node        string            `json:"node"`
+	Command     string            `json:"command"`
+	Env         map[string]string `json:"env,omitempty"`
+	Dir         string            `json:"dir,omitempty"`
+	Become      string            `json:"become,omitempty"`
+	StdinBytes  int               `json:"stdin_bytes"`
+	StdinSHA256 string            `json:"stdin_sha256,omitempty"`
+	Queued      time.Time         `json:"queued"`
+	Started     time.Time         `json:"started"`
+	Finished    time.Time         `json:"finished"`
+	ExitCode    int               `json:"exit_code"`
+	Signal      string            `json:"signal,omitempty"`
+	Attempts    int               `json:"attempts"`
+	Error       string            `json:"error,omitempty"`
+
+	// Hash of the previous record, and of this record, if chained
+	Prev string `json:"prev,omitempty"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// Audit appends an AuditRecord, as a line of JSON, to a file for every
+// Request executed on the Nodes it is set on. Commands and environment
+// variables are recorded with secrets redacted, and stdin only by size
+// and hash.
+type Audit struct {
+
+	// File to append records to
+	Path string
+
+	// Operator recorded as executing Requests. Defaults to the current user.
+	Operator string
+
+	// Size in bytes at which the file is rotated, by renaming it with a
+	// timestamp suffix. Zero means the file is never rotated.
+	MaxSize int64
+
+	// Number of rotated files to keep. Zero means all are kept.
+	MaxFiles int
+
+	// Chain records by hash, so that modifying or removing a record is
+	// detected by VerifyAudit. The chain continues across rotated files.
+	Chain bool
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	last     string
+	operator string
+}
+
+// Create an Audit appending to the file at path.
+func NewAudit(path string) *Audit {
+	return &Audit{Path: path}
+}
+
+// Set the Audit of each Node in the NodeList.
+func (l NodeList) SetAudit(audit *Audit) {
+	for _, n := range l {
+		n.Audit = audit
+	}
+}
+
+// Append a record, completing its Operator and hash chain.
+func (a *Audit) Record(rec AuditRecord) error {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+
+	if len(rec.Operator) == 0 {
+		rec.Operator = a.operator
+	}
+
+	if a.Chain {
+		rec.Prev = a.last
+		rec.Hash = ""
+		rec.Hash = hashRecord(rec)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if a.MaxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.MaxSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := a.file.Write(line); err != nil {
+		return err
+	}
+
+	a.size += int64(len(line))
+	a.last = rec.Hash
+	return nil
+}
+
+// Close the file.
+func (a *Audit) Close() error {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// Open the file, continuing the hash chain from its last record.
+func (a *Audit) open() error {
+
+	file, err := os.OpenFile(a.Path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if a.Chain && len(a.last) == 0 {
+		path := a.Path
+		if info.Size() == 0 {
+			if rotated := a.rotated(); len(rotated) > 0 {
+				path = rotated[len(rotated)-1]
+			}
+		}
+		if a.last, err = lastHash(path); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if len(a.operator) == 0 {
+		a.operator = a.defaultOperator()
+	}
+
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// Rename the file with a timestamp suffix, remove the oldest rotated
+// files beyond MaxFiles, and open a new file.
+func (a *Audit) rotate() error {
+
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	a.file = nil
+
+	suffix := time.Now().UTC().Format("20060102T150405.000000000")
+	if err := os.Rename(a.Path, a.Path+"."+suffix); err != nil {
+		return err
+	}
+
+	if rotated := a.rotated(); a.MaxFiles > 0 && len(rotated) > a.MaxFiles {
+		for _, path := range rotated[:len(rotated)-a.MaxFiles] {
+			os.Remove(path)
+		}
+	}
+
+	return a.open()
+}
+
+// Rotated files, oldest first.
+func (a *Audit) rotated() []string {
+	paths, _ := filepath.Glob(a.Path + ".[0-9]*T*")
+	sort.Strings(paths)
+	return paths
+}
+
+// The operator recorded by default, which is the current user unless
+// Operator is set.
+func (a *Audit) defaultOperator() string {
+	if len(a.Operator) > 0 {
+		return a.Operator
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Record the execution of a Request on the Node, if it has an Audit.
+func (n *Node) audit(req *Request, res Response) {
+
+	if n.Audit == nil {
+		return
+	}
+
+	rec := AuditRecord{
+		Node:       n.Name(),
+		Command:    n.Secrets.mask(redactCommand(req.Command), n.password(req)),
+		Dir:        n.workdir(req),
+		StdinBytes: len(req.Stdin),
+		Queued:     res.Queued.UTC(),
+		Started:    res.Started.UTC(),
+		Finished:   res.Finished.UTC(),
+		ExitCode:   res.ExitCode,
+		Signal:     res.Signal,
+		Attempts:   res.Attempts,
+	}
+
+	if env := n.environ(req); len(env) > 0 {
+		rec.Env = redactEnv(env)
+		for k, v := range rec.Env {
+			rec.Env[k] = n.Secrets.mask(v)
+		}
+	}
+
+	if b := req.Become; b != nil || n.Become != nil {
+		if b == nil {
+			b = n.Become
+		}
+		rec.Become = b.method() + ":" + b.user()
+	}
+
+	if len(req.Stdin) > 0 {
+		sum := sha256.Sum256(req.Stdin)
+		rec.StdinSHA256 = hex.EncodeToString(sum[:])
+	}
+
+	if res.Err != nil {
+		rec.Error = n.Secrets.mask(res.Err.Error(), n.password(req))
+	}
+
+	if err := n.Audit.Record(rec); err != nil {
+		n.log().Error("audit failed", "error", err)
+		n.emitError(err)
+	}
+}
+
+// Verify the hash chain of audit records, such as those of an Audit file
+// and its rotated files, read in order. The result is ErrAuditChain if a
+// record was modified, removed or inserted. The first record may continue
+// the chain of a file which has since been removed.
+func VerifyAudit(r io.Reader) error {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	var last string
+
+	for scanner.Scan() {
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec AuditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+
+		hash := rec.Hash
+		rec.Hash = ""
+
+		if (len(last) > 0 && rec.Prev != last) || hashRecord(rec) != hash {
+			return ErrAuditChain
+		}
+
+		last = hash
+	}
+
+	return scanner.Err()
+}
+
+// Hash of a record, chained to the previous record.
+func hashRecord(rec AuditRecord) string {
+	b, _ := json.Marshal(rec)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Hash of the last record in a file, if any.
+func lastHash(path string) (string, error) {
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	var last string
+
+	for scanner.Scan() {
+		var rec AuditRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) == nil && len(rec.Hash) > 0 {
+			last = rec.Hash
+		}
+	}
+
+	return last, scanner.Err()
+}
@@ -0,0 +1,153 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+// Contents of an audit file and its rotated files, oldest first.
+func readAudit(t *testing.T, path string) ([]byte, int) {
+
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(rotated)
+
+	var all bytes.Buffer
+	for _, p := range append(rotated, path) {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all.Write(content)
+	}
+
+	return all.Bytes(), len(rotated)
+}
+
+func TestAuditChain(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	audit := gommander.NewAudit(path)
+	audit.Chain = true
+	audit.MaxSize = 1024
+
+	n := gommander.NewFakeNode("node1", &gommander.FakeTransport{})
+	n.Audit = audit
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := gommander.Collect(gommander.NodeList{n}.Run("echo hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n.Close()
+	audit.Close()
+
+	// Reopening the file continues the chain
+	audit = gommander.NewAudit(path)
+	audit.Chain = true
+	if err := audit.Record(gommander.AuditRecord{Node: "node1", Command: "reopened"}); err != nil {
+		t.Fatal(err)
+	}
+	audit.Close()
+
+	content, rotated := readAudit(t, path)
+	if rotated == 0 {
+		t.Fatal("expected the file to be rotated")
+	}
+
+	if err := gommander.VerifyAudit(bytes.NewReader(content)); err != nil {
+		t.Fatalf("expected the chain to verify across %d rotated files: %v", rotated, err)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if len(lines) != 12 {
+		t.Fatalf("expected 11 records, got %d", len(lines)-1)
+	}
+
+	tampered := strings.Join(lines, "")
+	tampered = strings.Replace(tampered, "echo hello", "echo HELLO", 1)
+	if err := gommander.VerifyAudit(strings.NewReader(tampered)); err != gommander.ErrAuditChain {
+		t.Errorf("expected a modified record to break the chain, got %v", err)
+	}
+
+	removed := strings.Join(append(lines[:3:3], lines[4:]...), "")
+	if err := gommander.VerifyAudit(strings.NewReader(removed)); err != gommander.ErrAuditChain {
+		t.Errorf("expected a removed record to break the chain, got %v", err)
+	}
+}
+
+func TestAuditRecord(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	audit := gommander.NewAudit(path)
+	defer audit.Close()
+
+	n := gommander.NewFakeNode("node1", &gommander.FakeTransport{})
+	n.Audit = audit
+	n.Become = &gommander.Become{Password: "hunter2"}
+	connect(t, n)
+
+	_, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: "deploy --password=hunter2",
+		Env:     map[string]string{"API_TOKEN": "abc", "MODE": "prod"},
+		Stdin:   []byte("data"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rec gommander.AuditRecord
+	if err := json.Unmarshal(content, &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.Operator) == 0 {
+		t.Error("expected the current user to be recorded as operator")
+	}
+	if rec.Become != "sudo:root" {
+		t.Errorf("expected the resolved escalation to be recorded, got %q", rec.Become)
+	}
+	if strings.Contains(string(content), "hunter2") || strings.Contains(string(content), "abc") {
+		t.Errorf("secrets recorded in %s", content)
+	}
+	if rec.Env["MODE"] != "prod" {
+		t.Errorf("expected plain variables to be recorded, got %v", rec.Env)
+	}
+	if rec.StdinBytes != 4 || len(rec.StdinSHA256) != 64 {
+		t.Errorf("expected stdin to be recorded by size and hash, got %d, %q", rec.StdinBytes, rec.StdinSHA256)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// Privilege escalation methods.
+const (
+	BecomeSudo = "sudo"
+	BecomeSu   = "su"
+	BecomeDoas = "doas"
+)
+
+// ErrBecomeAuth is the Response error for a command which did not run,
+// because privilege escalation failed to authenticate.
+var ErrBecomeAuth = errors.New("Privilege escalation failed to authenticate.")
+
+// Become describes how to escalate privileges before running a command.
+// The password is never part of the command line. It is written to the
+// command's stdin when the password prompt appears. As su and doas only
+// read passwords from a terminal, a Pty is allocated for them when a
+// password is given.
+type Become struct {
+
+	// Method of escalation: BecomeSudo, BecomeSu or BecomeDoas.
+	// Defaults to BecomeSudo.
+	Method string
+
+	// User to become. Defaults to "root".
+	User string
+
+	// Password to answer the prompt with. If empty, the command fails
+	// instead of prompting.
+	Password string
+}
+
+// Matches the password prompts of su and doas.
+var passwordPrompt = regexp.MustCompile(`(?i)password[^\n]*:\s*$`)
+
+// Matches the messages of sudo, su and doas when authentication fails,
+// or a password is required but none was given.
+var authFailure = regexp.MustCompile(`(?i)password is required|authentication (failure|failed|required)|incorrect password|sorry, try again`)
+
+// An escalation tracks privilege escalation for a single command.
+// The wrapped command prints a marker once escalation succeeds, which is
+// stripped from the output. Until then, output is watched for the password
+// prompt.
+type escalation struct {
+	become *Become
+	marker string
+	prompt *regexp.Regexp
+
+	// Closed once the marker has been seen
+	ready chan struct{}
+
+	mu        sync.Mutex
+	out       io.Writer
+	stdin     io.Writer
+	abort     func()
+	buf       []byte
+	prompts   int
+	refused   bool
+	escalated bool
+}
+
+// Privilege escalation for a Request, if any.
+// The Request setting takes precedence over that of the Node.
+func (n *Node) escalation(req *Request) (*escalation, error) {
+
+	b := req.Become
+	if b == nil {
+		b = n.Become
+	}
+	if b == nil {
+		return nil, nil
+	}
+
+	switch b.Method {
+	case "", BecomeSudo, BecomeSu, BecomeDoas:
+	default:
+		return nil, errors.New("Unknown privilege escalation method: " + b.Method)
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	e := &escalation{
+		become: b,
+		marker: "gommander-ready-" + hex.EncodeToString(token),
+		ready:  make(chan struct{}),
+	}
+
+	if e.method() == BecomeSudo {
+		e.prompt = regexp.MustCompile(regexp.QuoteMeta(e.sudoPrompt()))
+	} else {
+		e.prompt = passwordPrompt
+	}
+
+	return e, nil
+}
+
+func (e *escalation) method() string {
+	return e.become.method()
+}
+
+// Prompt given to sudo, which is distinct from the marker.
+func (e *escalation) sudoPrompt() string {
+	return "gommander-password-" + e.marker[len("gommander-ready-"):]
+}
+
+func (e *escalation) user() string {
+	return e.become.user()
+}
+
+// The method of escalation, defaulting to BecomeSudo.
+func (b *Become) method() string {
+	if len(b.Method) == 0 {
+		return BecomeSudo
+	}
+	return b.Method
+}
+
+// The user to become, defaulting to root.
+func (b *Become) user() string {
+	if len(b.User) == 0 {
+		return "root"
+	}
+	return b.User
+}
+
+// Whether a Pty is needed to answer the password prompt.
+func (e *escalation) needsPty() bool {
+	return e.method() != BecomeSudo && len(e.become.Password) > 0
+}
+
+// Wrap the command to run as the target user.
+func (e *escalation) wrap(command string) string {
+
+	script := Quote("printf '%s\\n' " + e.marker + " >&2; " + command)
+	user := Quote(e.user())
+	prompt := len(e.become.Password) > 0
+
+	switch e.method() {
+	case BecomeSu:
+		return "su " + user + " -c " + script
+	case BecomeDoas:
+		if prompt {
+			return "doas -u " + user + " sh -c " + script
+		}
+		return "doas -n -u " + user + " sh -c " + script
+	default:
+		if prompt {
+			return "sudo -S -p " + Quote(e.sudoPrompt()) + " -u " + user + " -- sh -c " + script
+		}
+		return "sudo -n -u " + user + " -- sh -c " + script
+	}
+}
+
+// Watch the output stream which carries the password prompt.
+// The password is answered over stdin. If the prompt repeats, the
+// password was refused and abort is called.
+func (e *escalation) watch(out io.Writer, stdin io.Writer, abort func()) io.Writer {
+	e.out = out
+	e.stdin = stdin
+	e.abort = abort
+	return e
+}
+
+func (e *escalation) Write(p []byte) (int, error) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.escalated {
+		return e.out.Write(p)
+	}
+
+	e.buf = append(e.buf, p...)
+
+	for {
+		if i := bytes.Index(e.buf, []byte(e.marker)); i >= 0 {
+			rest := e.buf[i+len(e.marker):]
+			rest = bytes.TrimPrefix(rest, []byte("\r"))
+			rest = bytes.TrimPrefix(rest, []byte("\n"))
+			e.out.Write(e.buf[:i])
+			e.out.Write(rest)
+			e.buf = nil
+			e.escalated = true
+			close(e.ready)
+			break
+		}
+
+		loc := e.prompt.FindIndex(e.buf)
+		if loc == nil {
+			break
+		}
+
+		e.out.Write(e.buf[:loc[0]])
+		e.buf = append([]byte(nil), e.buf[loc[1]:]...)
+		e.prompts++
+
+		if e.prompts > 1 || len(e.become.Password) == 0 {
+			e.refused = true
+			e.abort()
+			break
+		}
+
+		io.WriteString(e.stdin, e.become.Password+"\n")
+	}
+
+	return len(p), nil
+}
+
+// Finish watching, returning ErrBecomeAuth if escalation failed to
+// authenticate. If escalation failed otherwise, such as the method not
+// being installed, the exit status of the command reports it.
+func (e *escalation) finish() error {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.escalated {
+		return nil
+	}
+
+	failed := e.refused || authFailure.Match(e.buf)
+
+	e.out.Write(e.buf)
+	e.buf = nil
+
+	if failed {
+		return ErrBecomeAuth
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	sudoMarker = regexp.MustCompile(`gommander-ready-[0-9a-f]+`)
+	sudoPrompt = regexp.MustCompile(`-p '(gommander-password-[0-9a-f]+)'`)
+)
+
+func TestEscalationWrap(t *testing.T) {
+
+	cases := []struct {
+		become   gommander.Become
+		prefix   string
+		needsPty bool
+	}{
+		{gommander.Become{}, "sudo -n -u 'root' -- sh -c ", false},
+		{gommander.Become{Password: "secret"}, "sudo -S -p 'gommander-password-", false},
+		{gommander.Become{Method: gommander.BecomeSu, User: "app"}, "su 'app' -c ", false},
+		{gommander.Become{Method: gommander.BecomeSu, Password: "secret"}, "su 'root' -c ", true},
+		{gommander.Become{Method: gommander.BecomeDoas}, "doas -n -u 'root' sh -c ", false},
+		{gommander.Become{Method: gommander.BecomeDoas, Password: "secret"}, "doas -u 'root' sh -c ", true},
+	}
+
+	for _, c := range cases {
+
+		b := c.become
+		n := &gommander.Node{Become: &b}
+
+		e, err := n.Escalation(&gommander.Request{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		command := e.Wrap("id -u")
+		if !strings.HasPrefix(command, c.prefix) {
+			t.Errorf("%+v: expected %q, got %q", b, c.prefix, command)
+		}
+		if !strings.Contains(command, e.Marker()) || !strings.HasSuffix(command, "id -u'") {
+			t.Errorf("%+v: expected the marker and command, got %q", b, command)
+		}
+		if strings.Contains(command, "secret") {
+			t.Errorf("%+v: password on the command line %q", b, command)
+		}
+		if e.NeedsPty() != c.needsPty {
+			t.Errorf("%+v: expected needsPty %v", b, c.needsPty)
+		}
+	}
+}
+
+func TestEscalationRequest(t *testing.T) {
+
+	n := &gommander.Node{Become: &gommander.Become{Method: gommander.BecomeSu}}
+
+	e, err := n.Escalation(&gommander.Request{Become: &gommander.Become{Method: gommander.BecomeDoas}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command := e.Wrap("id"); !strings.HasPrefix(command, "doas ") {
+		t.Errorf("expected the Request to take precedence, got %q", command)
+	}
+
+	if e, _ := (&gommander.Node{}).Escalation(&gommander.Request{}); e != nil {
+		t.Error("expected no escalation")
+	}
+
+	n.Become = &gommander.Become{Method: "pbrun"}
+	if _, err := n.Escalation(&gommander.Request{}); err == nil {
+		t.Error("expected an unknown method to be refused")
+	}
+}
+
+func TestEscalationWatch(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		password string
+		prompts  int
+		aborted  bool
+		err      error
+	}{
+		{"password", "secret", 1, false, nil},
+		{"refused", "secret", 2, true, gommander.ErrBecomeAuth},
+		{"no password", "", 1, true, gommander.ErrBecomeAuth},
+	}
+
+	for _, c := range cases {
+
+		n := &gommander.Node{Become: &gommander.Become{Password: c.password}}
+
+		e, err := n.Escalation(&gommander.Request{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prompt := "gommander-password-" + strings.TrimPrefix(e.Marker(), "gommander-ready-")
+		if len(c.password) > 0 {
+			prompt = sudoPrompt.FindStringSubmatch(e.Wrap("id"))[1]
+		}
+
+		var out, stdin bytes.Buffer
+		aborted := false
+		w := e.Watch(&out, &stdin, func() { aborted = true })
+
+		io.WriteString(w, "motd\n")
+		for i := 0; i < c.prompts && !aborted; i++ {
+			// The prompt may be split across writes
+			io.WriteString(w, prompt[:5])
+			io.WriteString(w, prompt[5:])
+		}
+		if !aborted {
+			io.WriteString(w, e.Marker()+"\nuid=0\n")
+		}
+
+		if aborted != c.aborted {
+			t.Errorf("%s: expected aborted %v", c.name, c.aborted)
+		}
+		if err := e.Finish(); err != c.err {
+			t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+		if c.err == nil && out.String() != "motd\nuid=0\n" {
+			t.Errorf("%s: expected the prompt and marker to be stripped, got %q", c.name, out.String())
+		}
+		if strings.Contains(out.String(), "gommander-") {
+			t.Errorf("%s: prompt or marker leaked into %q", c.name, out.String())
+		}
+		if len(c.password) > 0 && !strings.HasPrefix(stdin.String(), c.password+"\n") {
+			t.Errorf("%s: expected the password on stdin, got %q", c.name, stdin.String())
+		}
+	}
+}
+
+// Emulates sudo, which requires the password, allowing one retry.
+func sudo(password string) gommandertest.Handler {
+	return func(s *gommandertest.Session) (int, ssh.Signal) {
+
+		prompt := sudoPrompt.FindStringSubmatch(s.Command)
+		if prompt == nil {
+			io.WriteString(s.Stderr, "sudo: a password is required\n")
+			return 1, ""
+		}
+
+		stdin := bufio.NewReader(s.Stdin)
+
+		for attempt := 0; ; attempt++ {
+			if attempt == 2 {
+				io.WriteString(s.Stderr, "sudo: 2 incorrect password attempts\n")
+				return 1, ""
+			}
+			io.WriteString(s.Stderr, prompt[1])
+			line, err := stdin.ReadString('\n')
+			if err != nil {
+				return 1, ""
+			}
+			if line == password+"\n" {
+				break
+			}
+			io.WriteString(s.Stderr, "Sorry, try again.\n")
+		}
+
+		io.WriteString(s.Stderr, sudoMarker.FindString(s.Command)+"\n")
+
+		rest, _ := io.ReadAll(stdin)
+		io.WriteString(s.Stdout, "root:"+string(rest))
+		return 0, ""
+	}
+}
+
+func TestBecome(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		handler  gommandertest.Handler
+		password string
+		exitCode int
+		err      error
+		stdout   string
+	}{
+		{"password", sudo("secret"), "secret", 0, nil, "root:input"},
+		{"wrong password", sudo("secret"), "wrong", 1, gommander.ErrBecomeAuth, ""},
+		{"no password", sudo("secret"), "", 1, gommander.ErrBecomeAuth, ""},
+		{"not installed", gommandertest.Script(nil), "", 127, nil, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+
+			n := serve(t, gommandertest.Config{Handler: c.handler}).Node("test", nil)
+			n.Become = &gommander.Become{Password: c.password}
+			connect(t, n)
+
+			results, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+				Command: "id",
+				Stdin:   []byte("input"),
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := results.Responses[0]
+			if r.Err != c.err {
+				t.Errorf("expected error %v, got %v", c.err, r.Err)
+			}
+			if r.Err == nil && r.ExitCode != c.exitCode {
+				t.Errorf("expected exit %d, got %d", c.exitCode, r.ExitCode)
+			}
+			if r.Stdout.String() != c.stdout {
+				t.Errorf("expected stdout %q, got %q", c.stdout, r.Stdout)
+			}
+			if strings.Contains(r.Stderr.String(), "gommander-") {
+				t.Errorf("prompt or marker leaked into stderr %q", r.Stderr)
+			}
+			if len(c.password) > 0 && strings.Contains(r.Command, c.password) {
+				t.Errorf("password on the command line %q", r.Command)
+			}
+			if r.Err == nil && r.ExitCode == 0 && r.BytesIn != int64(len("input")) {
+				t.Errorf("expected only stdin to be counted, got %d bytes", r.BytesIn)
+			}
+		})
+	}
+}
+
+func TestBecomeFake(t *testing.T) {
+
+	for _, method := range []string{gommander.BecomeSudo, gommander.BecomeSu, gommander.BecomeDoas} {
+
+		fake := &gommander.FakeTransport{
+			Script: map[string]gommander.FakeResult{"id -u": {Stdout: "0\n"}},
+		}
+		n := gommander.NewFakeNode("node1", fake)
+		n.Become = &gommander.Become{Method: method, Password: "secret"}
+		connect(t, n)
+
+		results, err := gommander.Collect(gommander.NodeList{n}.Run("id -u"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := results.Responses[0]
+		if r.Err != nil || r.Stdout.String() != "0\n" {
+			t.Errorf("%s: unexpected response %q, %v", method, r.Stdout, r.Err)
+		}
+		if !strings.HasPrefix(r.Command, method+" ") {
+			t.Errorf("%s: command not wrapped: %q", method, r.Command)
+		}
+		if commands := fake.Commands(); len(commands) != 1 || commands[0] != "id -u" {
+			t.Errorf("%s: unexpected commands %q", method, commands)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ContainerTransport executes commands inside a container on another Node,
+// using the container runtime CLI on that Node. Commands are run with
+// "exec -i", so Write and Copy reach the filesystem of the container.
+// Signals are sent from inside the container, which needs /proc, sh, tr,
+// grep and kill.
+type ContainerTransport struct {
+
+	// Node the container runs on
+	Host *Node
+
+	// Name or ID of the container
+	Container string
+
+	// Container runtime CLI, such as "docker" or "podman".
+	// Defaults to "docker".
+	Runtime string
+
+	// User to execute commands as, inside the container.
+	// Defaults to the user of the container.
+	User string
+}
+
+// ErrHostNotConnected is returned when connecting a container Node whose
+// host Node is not connected.
+var ErrHostNotConnected = errors.New("Host not connected.")
+
+// Create a Node for a container on the host Node. The Node is named
+// after the host and container, so a NodeList can mix both. The host is
+// not connected or closed with the container Node, so it can be shared:
+// connect it first, and close it once its containers are closed.
+func NewContainerNode(host *Node, container string) *Node {
+	return &Node{
+		Host: host.Host + "/" + container,
+		Port: host.Port,
+		User: host.User,
+		Transport: &ContainerTransport{
+			Host:      host,
+			Container: container,
+		},
+	}
+}
+
+// Check the host Node is connected. The host is left to its owner.
+func (t *ContainerTransport) Connect(n *Node) error {
+
+	if t.Host == nil {
+		return errors.New("No host for container.")
+	}
+
+	if state := t.Host.State(); state != Idle && state != Busy {
+		return ErrHostNotConnected
+	}
+
+	return nil
+}
+
+// Open a session on the host, which runs commands in the container.
+func (t *ContainerTransport) NewSession() (Session, error) {
+
+	transport := t.Host.transport()
+	if transport == nil {
+		return nil, ErrNotConnected
+	}
+
+	session, err := transport.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	return &containerSession{
+		Session:   session,
+		transport: t,
+		env:       map[string]string{},
+		job:       hex.EncodeToString(token),
+	}, nil
+}
+
+// Nothing to release, as sessions are closed with their commands, and the
+// host is left to its owner.
+func (t *ContainerTransport) Close() error {
+	return nil
+}
+
+// A session on the host, whose commands are wrapped to run in the container.
+// Environment variables are passed to the runtime, as the host server
+// would not pass them through. The runtime does not forward signals, so
+// the processes of the command are marked with a job variable, and
+// signalled from within the container by it.
+type containerSession struct {
+	Session
+	transport *ContainerTransport
+	env       map[string]string
+	job       string
+	tty       bool
+}
+
+// Environment variable marking the processes of a command in a container.
+const containerJobVar = "GOMMANDER_JOB"
+
+func (s *containerSession) Setenv(name, value string) error {
+	s.env[name] = value
+	return nil
+}
+
+func (s *containerSession) RequestPty(term string, height, width int, modes ssh.TerminalModes) error {
+	if err := s.Session.RequestPty(term, height, width, modes); err != nil {
+		return err
+	}
+	s.tty = true
+	return nil
+}
+
+func (s *containerSession) runtime() string {
+	if len(s.transport.Runtime) == 0 {
+		return "docker"
+	}
+	return s.transport.Runtime
+}
+
+// Wrap the command to run in the container.
+func (s *containerSession) wrap(command string) string {
+
+	exec := s.runtime() + " exec -i"
+
+	if s.tty {
+		exec += " -t"
+	}
+
+	if len(s.transport.User) > 0 {
+		exec += " -u " + Quote(s.transport.User)
+	}
+
+	keys := make([]string, 0, len(s.env))
+	for k := range s.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		exec += " -e " + Quote(k+"="+s.env[k])
+	}
+
+	exec += " -e " + Quote(containerJobVar+"="+s.job)
+
+	return exec + " " + Quote(s.transport.Container) + " sh -c " + Quote(command)
+}
+
+func (s *containerSession) Start(command string) error {
+	return s.Session.Start(s.wrap(command))
+}
+
+// Signal the processes of the command inside the container, as root,
+// through another session on the host.
+func (s *containerSession) Signal(sig ssh.Signal) error {
+
+	transport := s.transport.Host.transport()
+	if transport == nil {
+		return ErrNotConnected
+	}
+
+	session, err := transport.NewSession()
+	if err != nil {
+		return err
+	}
+
+	defer session.Close()
+
+	script := "for p in /proc/[0-9]*; do " +
+		"tr '\\0' '\\n' < $p/environ 2>/dev/null | grep -qx " + Quote(containerJobVar+"="+s.job) +
+		" && kill -s " + string(sig) + " ${p#/proc/} 2>/dev/null; done; true"
+
+	session.SetOutput(nil, nil)
+
+	command := s.runtime() + " exec -u 0 " + Quote(s.transport.Container) + " sh -c " + Quote(script)
+	if err := session.Start(command); err != nil {
+		return err
+	}
+
+	return session.Wait()
+}
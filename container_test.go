@@ -0,0 +1,145 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+)
+
+// A container runtime which runs "exec" commands on the host, with the
+// variables given by -e.
+const stubRuntime = `#!/bin/sh
+[ "$1" = exec ] || exit 2
+shift
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-i|-t) shift ;;
+	-u) shift 2 ;;
+	-e) export "$2"; shift 2 ;;
+	*) break ;;
+	esac
+done
+shift
+exec "$@"
+`
+
+// A container Node on a connected local host, using the stub runtime.
+func newContainerNode(t *testing.T) *gommander.Node {
+
+	t.Helper()
+
+	stub := filepath.Join(t.TempDir(), "runtime")
+	if err := os.WriteFile(stub, []byte(stubRuntime), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	host := connect(t, gommander.NewLocalNode("host"))[0]
+
+	n := gommander.NewContainerNode(host, "app")
+	n.Transport.(*gommander.ContainerTransport).Runtime = stub
+
+	return n
+}
+
+func TestContainer(t *testing.T) {
+
+	n := newContainerNode(t)
+	n.Env = map[string]string{"GREETING": "it's me"}
+	connect(t, n)
+
+	if n.Host != "host/app" {
+		t.Errorf("unexpected name %q", n.Host)
+	}
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: `echo "$GREETING"; cat`,
+		Stdin:   []byte("input"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := results.Responses[0]
+	if r.Err != nil || r.Stdout.String() != "it's me\ninput" {
+		t.Errorf("unexpected response %q, %q, %v", r.Stdout, r.Stderr, r.Err)
+	}
+
+	// The command recorded is the one run on the host
+	if !strings.Contains(r.Command, " exec -i ") || !strings.Contains(r.Command, "'app' sh -c ") {
+		t.Errorf("expected the wrapped command, got %q", r.Command)
+	}
+}
+
+func TestContainerHost(t *testing.T) {
+
+	host := gommander.NewLocalNode("host")
+
+	// The host is not connected with its containers
+	if err := gommander.NewContainerNode(host, "app").Connect(); err != gommander.ErrHostNotConnected {
+		t.Fatalf("expected ErrHostNotConnected, got %v", err)
+	}
+
+	nodes := gommander.NodeList{host, gommander.NewContainerNode(host, "app"), gommander.NewContainerNode(host, "db")}
+	if err := nodes.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor closed with them, while other containers use it
+	if err := nodes[1].Close(); err != nil {
+		t.Fatal(err)
+	}
+	if state := host.State(); state != gommander.Idle {
+		t.Errorf("expected the host to stay connected, got %s", state)
+	}
+
+	if err := nodes[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := nodes[2].Close(); err != nil {
+		t.Errorf("expected the container to close after its host, got %v", err)
+	}
+}
+
+func TestContainerCancel(t *testing.T) {
+
+	if runtime.GOOS != "linux" {
+		t.Skip("signals in containers need /proc")
+	}
+
+	n := newContainerNode(t)
+	connect(t, n)
+
+	job, responses := start(t, n, "sleep 30 | cat")
+
+	sent := time.Now()
+	if err := job.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := response(t, responses)
+	if r.Err == nil && r.ExitCode == 0 {
+		t.Error("expected the cancelled command to fail")
+	}
+	if d := time.Since(sent); d > 3*time.Second {
+		t.Errorf("command was not killed inside the container, took %s", d)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Quote a string for use as a single word in a POSIX shell command.
+func Quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Merge the environment of the Request with that of the Node.
+// Node variables take precedence over Request variables.
+func (n *Node) environ(req *Request) map[string]string {
+	env := map[string]string{}
+	for k, v := range req.Env {
+		env[k] = v
+	}
+	for k, v := range n.Env {
+		env[k] = v
+	}
+	return env
+}
+
+// Working directory for a Request, taking the Node override into account.
+func (n *Node) workdir(req *Request) string {
+	if len(n.Dir) > 0 {
+		return n.Dir
+	}
+	return req.Dir
+}
+
+// Apply the environment and working directory of a Request to the session,
+// and return the command to run.
+// If setenv is true, variables are sent with Setenv. Most servers only accept
+// the names listed in their AcceptEnv setting, so if any is refused, all of
+// the variables are exported by a shell prefix on the command instead.
+func (n *Node) prepare(session Session, req *Request, setenv bool) (string, error) {
+
+	env := n.environ(req)
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if !validEnvName(k) {
+			return "", errors.New("Invalid environment variable name: " + k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Variables accepted by the server need no prefix.
+	if setenv && sendEnv(session, env, keys) {
+		keys = nil
+	}
+
+	return shellPrefix(env, keys, n.workdir(req)) + req.Command, nil
+}
+
+// Send the variables with Setenv, returning false if any is refused.
+func sendEnv(session Session, env map[string]string, keys []string) bool {
+	for _, k := range keys {
+		if err := session.Setenv(k, env[k]); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Build a shell prefix which exports the given variables and changes to dir.
+func shellPrefix(env map[string]string, keys []string, dir string) string {
+
+	var prefix bytes.Buffer
+
+	for _, k := range keys {
+		prefix.WriteString("export " + k + "=" + Quote(env[k]) + "; ")
+	}
+
+	if len(dir) > 0 {
+		prefix.WriteString("cd " + Quote(dir) + " || exit 1; ")
+	}
+
+	return prefix.String()
+}
+
+// Check that name is a valid shell variable name.
+func validEnvName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+func TestShellPrefix(t *testing.T) {
+
+	env := map[string]string{"A": "1", "B": "it's"}
+
+	cases := []struct {
+		keys     []string
+		dir      string
+		expected string
+	}{
+		{nil, "", ""},
+		{[]string{"A", "B"}, "", `export A='1'; export B='it'\''s'; `},
+		{nil, "/tmp/a b", `cd '/tmp/a b' || exit 1; `},
+		{[]string{"A"}, "/tmp", `export A='1'; cd '/tmp' || exit 1; `},
+	}
+
+	for _, c := range cases {
+		if prefix := gommander.ShellPrefix(env, c.keys, c.dir); prefix != c.expected {
+			t.Errorf("shellPrefix(%q, %q) = %q, expected %q", c.keys, c.dir, prefix, c.expected)
+		}
+	}
+}
+
+func TestNodeOverrides(t *testing.T) {
+
+	n := &gommander.Node{
+		Env: map[string]string{"B": "node", "C": "3"},
+		Dir: "/node",
+	}
+
+	req := &gommander.Request{
+		Command: "env",
+		Env:     map[string]string{"A": "1", "B": "request"},
+		Dir:     "/request",
+	}
+
+	expected := map[string]string{"A": "1", "B": "node", "C": "3"}
+	if env := n.Environ(req); !reflect.DeepEqual(env, expected) {
+		t.Errorf("environ = %v, expected %v", env, expected)
+	}
+	if dir := n.Workdir(req); dir != "/node" {
+		t.Errorf("workdir = %q, expected the Node directory", dir)
+	}
+
+	n.Dir = ""
+	if dir := n.Workdir(req); dir != "/request" {
+		t.Errorf("workdir = %q, expected the Request directory", dir)
+	}
+}
+
+func TestValidEnvName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"":        false,
+		"A":       true,
+		"_a1":     true,
+		"1A":      false,
+		"A-B":     false,
+		"A B":     false,
+		"A;rm -r": false,
+	} {
+		if gommander.ValidEnvName(name) != valid {
+			t.Errorf("validEnvName(%q) != %v", name, valid)
+		}
+	}
+}
+
+func TestEnvAndDir(t *testing.T) {
+
+	for _, reject := range []bool{false, true} {
+
+		server := serve(t, gommandertest.Config{RejectEnv: reject})
+
+		n := server.Node("test", nil)
+		n.Env = map[string]string{"GREETING": "it's node"}
+		connect(t, n)
+
+		dir := t.TempDir()
+
+		results, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+			Command: `printf '%s|%s|' "$GREETING" "$OTHER"; pwd`,
+			Env:     map[string]string{"GREETING": "request", "OTHER": "x y"},
+			Dir:     dir,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := results.Responses[0]
+		if expected := "it's node|x y|" + dir + "\n"; r.Stdout.String() != expected {
+			t.Errorf("RejectEnv %v: expected %q, got %q (%q)", reject, expected, r.Stdout, r.Stderr)
+		}
+
+		// Refused variables are exported by the command instead
+		if prefixed := strings.Contains(r.Command, "export GREETING="); prefixed != reject {
+			t.Errorf("RejectEnv %v: unexpected command %q", reject, r.Command)
+		}
+	}
+}
+
+func TestMissingDir(t *testing.T) {
+
+	n := connect(t, serve(t, gommandertest.Config{}).Node("test", nil))[0]
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: "echo ran",
+		Dir:     "/nonexistent/gommander",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := results.Responses[0]; r.ExitCode != 1 || strings.Contains(r.Stdout.String(), "ran") {
+		t.Errorf("expected the command not to run, got exit %d, stdout %q", r.ExitCode, r.Stdout)
+	}
+}
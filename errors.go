@@ -0,0 +1,68 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bytes"
+	"strings"
+)
+
+// NodeError is an error of an operation on a particular Node.
+type NodeError struct {
+
+	// Node the operation failed on
+	Node *Node
+
+	// Error of the operation
+	Err error
+}
+
+func (e *NodeError) Error() string {
+	return e.Node.Name() + ": " + e.Err.Error()
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// NodeErrors are the errors of an operation against a NodeList,
+// for each Node it failed on.
+type NodeErrors []*NodeError
+
+func (e NodeErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e NodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// A Response for a Node on which a Request could not be executed.
+func errorResponse(n *Node, err error) Response {
+	return Response{
+		Node:   n,
+		Stdout: new(bytes.Buffer),
+		Stderr: new(bytes.Buffer),
+		Err:    err,
+	}
+}
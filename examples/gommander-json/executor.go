@@ -16,65 +16,16 @@ package main
 
 import (
 	. "github.com/aerospike/gommander"
-
-	"fmt"
-	"strings"
-	"time"
+	"github.com/aerospike/gommander/format"
 )
 
 type Executor struct {
-	colorMap *ColorMap
+	formatter format.Formatter
 }
 
-func NewExecutor() *Executor {
+func NewExecutor(formatter format.Formatter) *Executor {
 	return &Executor{
-		colorMap: NewColorMap(),
-	}
-}
-
-func (e *Executor) printResponses(responses chan Response, err error) {
-
-	const layout = "2006-01-02 15:04:05 -0700"
-	outtag := color(column(" OUT", 5), IBlack)
-	errtag := color(column(" ERR", 5), Yellow+BIRed)
-	septag := color("]", IBlack)
-
-	if err != nil {
-		panic(err)
-	}
-
-	for r := range responses {
-
-		println(color("--------------------------------------------------------------------------------", IBlack))
-
-		exittag := color(column(fmt.Sprintf("%d", r.ExitCode), 3), White)
-		datetime := time.Now()
-		datetimetag := color(datetime.Format(layout), IBlack)
-		hosttag := color(column(r.Node.Host, 15), e.colorMap.Get(r.Node.Host))
-		epre := fmt.Sprintf("%s  %20s %s %s %s  ", datetimetag, hosttag, errtag, exittag, septag)
-		opre := fmt.Sprintf("%s  %20s %s %s %s  ", datetimetag, hosttag, outtag, exittag, septag)
-
-		ostr := strings.Trim(r.Stdout.String(), "\r\n ")
-		if len(ostr) > 0 {
-			for _, s := range strings.Split(ostr, "\n") {
-				fmt.Println(opre + s)
-			}
-		} else {
-			if r.ExitCode == 0 {
-				fmt.Println(opre + "<SUCCESS>")
-			}
-		}
-
-		estr := strings.Trim(r.Stderr.String(), "\r\n ")
-		if len(estr) > 0 {
-			for _, s := range strings.Split(estr, "\n") {
-				fmt.Println(epre + s)
-			}
-		} else {
-			if r.ExitCode != 0 {
-				fmt.Println(epre + "<FAILURE>")
-			}
-		}
+		formatter: formatter,
 	}
 }
 
@@ -82,6 +33,5 @@ func (e *Executor) exec(desc string, fn func() (chan Response, error)) {
 
 	println("################################################################################")
 	println(">", desc)
-	resp, err := fn()
-	e.printResponses(resp, err)
+	e.formatter.Print(fn())
 }
@@ -16,7 +16,9 @@ package main
 
 import (
 	. "github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/format"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 
 	"encoding/json"
 	"flag"
@@ -39,8 +41,15 @@ func main() {
 
 	hosts_file := flag.String("hosts", "hosts.json", "A JSON file listing the hosts.")
 	commands_file := flag.String("commands", "commands.json", "A JSON file listing the commands to execute.")
+	output := flag.String("format", "text", "Output format: text, table, json or jsonl.")
 	flag.Parse()
 
+	formatter, err := format.New(*output, os.Stdout)
+	if err != nil {
+		fmt.Printf("error: Invalid format '%s'\n", *output)
+		os.Exit(1)
+	}
+
 	hosts_json, err := ioutil.ReadFile(*hosts_file)
 	if err != nil {
 		fmt.Printf("Failed to read '%s'\n", *hosts_file)
@@ -94,11 +103,18 @@ func main() {
 		nodes = append(nodes, NewNode(h.Host, h.Port, h.Username, auth))
 	}
 
+	// Verify host keys against the known hosts
+	hostKey, err := knownhosts.New(os.ExpandEnv("$HOME/.ssh/known_hosts"))
+	if err != nil {
+		panic(err)
+	}
+	nodes.SetHostKey(hostKey)
+
 	// connect to the nodes
 	nodes.Connect()
 
 	// setup executor
-	executor := NewExecutor()
+	executor := NewExecutor(formatter)
 
 	// run the commands
 	for _, c := range commands {
@@ -3,18 +3,26 @@ package main
 import (
 	. "github.com/aerospike/gommander"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 
 	"fmt"
+	"os"
 )
 
 func printResponse(responses chan Response, err error) {
 	if err != nil {
-		panic(err)
+		fmt.Printf("\033[0;91m%s\033[0m\n", err)
+	}
+
+	if responses == nil {
+		return
 	}
 
 	for r := range responses {
-		switch r.ExitCode {
-		case 0: // Success!
+		switch {
+		case r.Err != nil: // Error!
+			fmt.Printf("\033[0;91m%s > %s\033[0m\n", r.Node.Host, r.Err)
+		case r.ExitCode == 0: // Success!
 			fmt.Printf("\033[0;97m%s > %s\033[0m\n", r.Node.Host, r.Stdout.String())
 		default: // Failure!
 			fmt.Printf("\033[0;91m%s > %s\033[0m\n", r.Node.Host, r.Stderr.String())
@@ -39,6 +47,13 @@ func main() {
 		NewNode("127.0.0.1", 2222, "vagrant", authMethods),
 	}
 
+	// Verify host keys against the known hosts
+	hostKey, err := knownhosts.New(os.ExpandEnv("$HOME/.ssh/known_hosts"))
+	if err != nil {
+		panic(err)
+	}
+	nodes.SetHostKey(hostKey)
+
 	// Connect to the nodes
 	nodes.Connect()
 
@@ -2,86 +2,26 @@ package main
 
 import (
 	. "github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/format"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 
-	"bytes"
 	"flag"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
-// Dump function for printing fixed width columns
-func column(s string, w int) string {
-	o := bytes.Buffer{}
-	i := 0
-	for ; i < len(s); i++ {
-		o.WriteRune(rune(s[i]))
-		if i >= w {
-			break
-		}
-	}
-	for j := i; j < w; j++ {
-		o.WriteRune(' ')
-	}
-	return o.String()
-}
-
-// Iterate over responses recevied for a command
-// and print it to stdout
-func printResponses(responses chan Response, err error) {
-
-	const layout = "2006-01-02 15:04:05 -0700"
-
-	date := time.Now().Format(layout)
-	sep := "]"
-
-	if err != nil {
-		panic(err)
-	}
-
-	for r := range responses {
-
-		println("--------------------------------------------------------------------------------")
-
-		exit := column(fmt.Sprintf("%d", r.ExitCode), 3)
-		host := column(r.Node.Host, 15)
-
-		epre := fmt.Sprintf("\033[0;91m%s %s %s %s %s  \033[0m", date, host, column(" ERR", 5), exit, sep)
-		opre := fmt.Sprintf("\033[0;90m%s %s %s %s %s  \033[0m", date, host, column(" OUT", 5), exit, sep)
-
-		ostr := strings.Trim(r.Stdout.String(), "\r\n ")
-		if len(ostr) > 0 {
-			for _, s := range strings.Split(ostr, "\n") {
-				fmt.Println(opre + s)
-			}
-		} else {
-			if r.ExitCode == 0 {
-				fmt.Println(opre + "<SUCCESS>")
-			}
-		}
-
-		estr := strings.Trim(r.Stderr.String(), "\r\n ")
-		if len(estr) > 0 {
-			for _, s := range strings.Split(estr, "\n") {
-				fmt.Println(epre + s)
-			}
-		} else {
-			if r.ExitCode != 0 {
-				fmt.Println(epre + "<FAILURE>")
-			}
-		}
-	}
-}
+// Formatter for printing responses
+var formatter format.Formatter
 
 // Print the description, run the command, then print the results... easy
 func run(desc string, fn func() (chan Response, error)) {
 
 	println("################################################################################")
 	println(">", desc)
-	resp, err := fn()
-	printResponses(resp, err)
+	formatter.Print(fn())
 }
 
 func parseCred(s string) (user string, pass string) {
@@ -143,8 +83,14 @@ func main() {
 	username := flag.String("username", "", "Username for connecting to servers.")
 	password := flag.String("password", "", "Password for connecting to servers.")
 	sshkeyfile := flag.String("sshkey", "", "SSH Key file for connecting to servers.")
+	output := flag.String("format", "text", "Output format: text, table, json or jsonl.")
 	flag.Parse()
 
+	var err error
+	if formatter, err = format.New(*output, os.Stdout); err != nil {
+		panic(err)
+	}
+
 	// SSH Key Auth
 	var sshkey *ssh.Signer
 
@@ -190,6 +136,13 @@ func main() {
 		nodes = append(nodes, NewNode(host, uint(port), user, authMethods))
 	}
 
+	// Verify host keys against the known hosts
+	hostKey, err := knownhosts.New(os.ExpandEnv("$HOME/.ssh/known_hosts"))
+	if err != nil {
+		panic(err)
+	}
+	nodes.SetHostKey(hostKey)
+
 	// Connect to the nodes
 	nodes.Connect()
 
@@ -0,0 +1,125 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"io"
+	"time"
+)
+
+// Internals exported for the tests of package gommander_test.
+
+var (
+	ShellPrefix    = shellPrefix
+	ValidEnvName   = validEnvName
+	FoldNumbers    = foldNumbers
+	SOCKSHandshake = socksHandshake
+	RedactCommand  = redactCommand
+	RedactEnv      = redactEnv
+)
+
+func (n *Node) Environ(req *Request) map[string]string {
+	return n.environ(req)
+}
+
+func (n *Node) Workdir(req *Request) string {
+	return n.workdir(req)
+}
+
+type Escalation = escalation
+
+func (n *Node) Escalation(req *Request) (*Escalation, error) {
+	return n.escalation(req)
+}
+
+func (e *escalation) Marker() string {
+	return e.marker
+}
+
+func (e *escalation) NeedsPty() bool {
+	return e.needsPty()
+}
+
+func (e *escalation) Wrap(command string) string {
+	return e.wrap(command)
+}
+
+func (e *escalation) Watch(out io.Writer, stdin io.Writer, abort func()) io.Writer {
+	return e.watch(out, stdin, abort)
+}
+
+func (e *escalation) Finish() error {
+	return e.finish()
+}
+
+var NewJob = newJob
+
+func (j *Job) Attach(session Session) bool {
+	return j.attach(session)
+}
+
+func NewCountWriter(w io.Writer, n *int64) io.Writer {
+	return &countWriter{w: w, n: n}
+}
+
+// Write output as a command would, completing the Response.
+func WriteOutput(n *Node, req *Request, res *Response, stdout, stderr string) error {
+
+	out, err := newOutput(n, req, res)
+	if err != nil {
+		return err
+	}
+
+	io.WriteString(out.stdout, stdout)
+	io.WriteString(out.stderr, stderr)
+
+	return out.close(res)
+}
+
+func (p *Retry) Retryable(res Response) bool {
+	return p.retryable(res)
+}
+
+func (p *Retry) Delay(attempt int) time.Duration {
+	return p.delay(attempt)
+}
+
+func (j *Job) Sleep(d time.Duration) bool {
+	return j.sleep(d)
+}
+
+func (p Policy) Required(total int) int {
+	return p.required(total)
+}
+
+const (
+	RedactedValue    = redactedValue
+	SOCKSUnsupported = socksUnsupported
+	SOCKSBadAddress  = socksBadAddress
+)
+
+func (s *Secrets) MaskExtra(text string, extra ...string) string {
+	return s.mask(text, extra...)
+}
+
+func (s *Secrets) MaskError(err error) error {
+	return s.maskError(err)
+}
+
+func (n *Node) Closers() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.closers)
+}
@@ -0,0 +1,270 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// FakeResult is the scripted result of a command run by a FakeTransport.
+type FakeResult struct {
+
+	// Output of the command
+	Stdout string
+	Stderr string
+
+	// Exit status of the command
+	ExitCode int
+
+	// Time the command takes to run
+	Delay time.Duration
+
+	// Error returned instead of running the command
+	Err error
+}
+
+// FakeTransport executes commands in memory, responding with scripted
+// results. It allows code built on gommander to be tested without servers.
+// Privilege escalation always succeeds, and commands are matched and
+// recorded without its wrapper.
+type FakeTransport struct {
+
+	// Results for each command, matched exactly
+	Script map[string]FakeResult
+
+	// Computes the result of commands missing from the Script, if set.
+	// It receives the Node, the command and its stdin.
+	Handler func(n *Node, command string, stdin []byte) FakeResult
+
+	// Result for commands missing from the Script, if there is no Handler
+	Default FakeResult
+
+	node     *Node
+	mu       sync.Mutex
+	commands []string
+}
+
+// Create a Node which executes commands with a FakeTransport.
+func NewFakeNode(host string, t *FakeTransport) *Node {
+	return &Node{
+		Host:      host,
+		Port:      22,
+		Transport: t,
+	}
+}
+
+// Commands executed by the FakeTransport, in order.
+func (t *FakeTransport) Commands() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.commands...)
+}
+
+// Connect binds the FakeTransport to the Node.
+func (t *FakeTransport) Connect(n *Node) error {
+	t.node = n
+	return nil
+}
+
+// Open a session, which responds according to the script.
+func (t *FakeTransport) NewSession() (Session, error) {
+	if t.node == nil {
+		return nil, ErrNotConnected
+	}
+	return &fakeSession{fake: t, signals: make(chan ssh.Signal, 1)}, nil
+}
+
+// Close does nothing, as there is nothing to disconnect from.
+func (t *FakeTransport) Close() error {
+	return nil
+}
+
+// The result of a command.
+func (t *FakeTransport) result(command string, stdin []byte) FakeResult {
+
+	t.mu.Lock()
+	t.commands = append(t.commands, command)
+	res, ok := t.Script[command]
+	t.mu.Unlock()
+
+	switch {
+	case ok:
+		return res
+	case t.Handler != nil:
+		return t.Handler(t.node, command, stdin)
+	default:
+		return t.Default
+	}
+}
+
+// Exit statuses for signals, as reported by ssh.ExitError.
+var signalStatus = map[ssh.Signal]int{
+	ssh.SIGHUP:  1,
+	ssh.SIGINT:  2,
+	ssh.SIGQUIT: 3,
+	ssh.SIGKILL: 9,
+	ssh.SIGTERM: 15,
+}
+
+// Matches a command wrapped for privilege escalation, capturing its
+// quoted script.
+var escalationWrapper = regexp.MustCompile(`(?s)^(?:sudo|su|doas) .*? -c ('.*')$`)
+
+// Matches the escalation marker at the start of a script.
+var escalationMarker = regexp.MustCompile(`^printf '%s\\n' (gommander-ready-[0-9a-f]+) >&2; `)
+
+// Unwrap a command wrapped for privilege escalation, returning the
+// original command and the marker which signals escalation succeeded.
+func unwrapEscalation(command string) (string, string, bool) {
+
+	m := escalationWrapper.FindStringSubmatch(command)
+	if m == nil {
+		return command, "", false
+	}
+
+	script := strings.ReplaceAll(m[1][1:len(m[1])-1], `'\''`, `'`)
+
+	marker := escalationMarker.FindStringSubmatch(script)
+	if marker == nil {
+		return command, "", false
+	}
+
+	return script[len(marker[0]):], marker[1], true
+}
+
+type fakeSession struct {
+	fake    *FakeTransport
+	pty     bool
+	stdin   *io.PipeReader
+	stdout  io.Writer
+	stderr  io.Writer
+	signals chan ssh.Signal
+	done    chan error
+}
+
+func (s *fakeSession) Setenv(name, value string) error {
+	return nil
+}
+
+func (s *fakeSession) RequestPty(term string, height, width int, modes ssh.TerminalModes) error {
+	s.pty = true
+	return nil
+}
+
+func (s *fakeSession) WindowChange(height, width int) error {
+	return nil
+}
+
+func (s *fakeSession) Signal(sig ssh.Signal) error {
+	select {
+	case s.signals <- sig:
+	default:
+	}
+	return nil
+}
+
+func (s *fakeSession) StdinPipe() (io.WriteCloser, error) {
+	r, w := io.Pipe()
+	s.stdin = r
+	return w, nil
+}
+
+func (s *fakeSession) SetOutput(stdout, stderr io.Writer) {
+	s.stdout = stdout
+	s.stderr = stderr
+}
+
+func (s *fakeSession) Start(command string) error {
+
+	s.done = make(chan error, 1)
+
+	go func() {
+
+		// Escalation always succeeds, and the result is that of the
+		// original command. Stdin is held back until the marker is seen.
+		if inner, marker, ok := unwrapEscalation(command); ok {
+			command = inner
+			if s.pty {
+				write(s.stdout, marker+"\r\n")
+			} else {
+				write(s.stderr, marker+"\n")
+			}
+		}
+
+		// The result may depend on stdin, which is written once started
+		var stdin []byte
+		if s.stdin != nil {
+			stdin, _ = ioutil.ReadAll(s.stdin)
+		}
+
+		res := s.fake.result(command, stdin)
+		if res.Err != nil {
+			s.done <- res.Err
+			return
+		}
+
+		select {
+		case <-time.After(res.Delay):
+		case sig := <-s.signals:
+			s.done <- NewExitError(128+signalStatus[sig], string(sig))
+			return
+		}
+
+		// A Pty merges stderr into stdout
+		write(s.stdout, res.Stdout)
+		if s.pty {
+			write(s.stdout, res.Stderr)
+		} else {
+			write(s.stderr, res.Stderr)
+		}
+
+		if res.ExitCode != 0 {
+			s.done <- NewExitError(res.ExitCode, "")
+		} else {
+			s.done <- nil
+		}
+	}()
+
+	return nil
+}
+
+func (s *fakeSession) Wait() error {
+	if s.done == nil {
+		return errors.New("Not started.")
+	}
+	return <-s.done
+}
+
+func (s *fakeSession) Close() error {
+	if s.stdin != nil {
+		s.stdin.Close()
+	}
+	return s.Signal(ssh.SIGKILL)
+}
+
+func write(w io.Writer, s string) {
+	if w != nil {
+		io.Copy(w, bytes.NewReader([]byte(s)))
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestFake(t *testing.T) {
+
+	fake := &gommander.FakeTransport{
+		Script: map[string]gommander.FakeResult{
+			"hostname": {Stdout: "node1\n"},
+			"false":    {ExitCode: 1, Stderr: "failed\n"},
+		},
+		Handler: func(n *gommander.Node, command string, stdin []byte) gommander.FakeResult {
+			return gommander.FakeResult{Stdout: n.Host + ":" + command + ":" + string(stdin)}
+		},
+	}
+	n := connect(t, gommander.NewFakeNode("node1", fake))[0]
+
+	cases := []struct {
+		req      gommander.Request
+		exitCode int
+		stdout   string
+		stderr   string
+	}{
+		{gommander.Request{Command: "hostname"}, 0, "node1\n", ""},
+		{gommander.Request{Command: "false"}, 1, "", "failed\n"},
+		{gommander.Request{Command: "cat", Stdin: []byte("in")}, 0, "node1:cat:in", ""},
+	}
+
+	for _, c := range cases {
+
+		results, err := gommander.Collect(gommander.NodeList{n}.Execute(c.req))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := results.Responses[0]
+		if r.Err != nil || r.ExitCode != c.exitCode || r.Stdout.String() != c.stdout || r.Stderr.String() != c.stderr {
+			t.Errorf("%s: unexpected response: exit %d, stdout %q, stderr %q, error %v",
+				c.req.Command, r.ExitCode, r.Stdout, r.Stderr, r.Err)
+		}
+		if r.Command != c.req.Command || r.BytesIn != int64(len(c.req.Stdin)) {
+			t.Errorf("%s: unexpected command %q, %d bytes in", c.req.Command, r.Command, r.BytesIn)
+		}
+	}
+
+	if commands := fake.Commands(); len(commands) != 3 || commands[0] != "hostname" {
+		t.Errorf("unexpected commands %q", commands)
+	}
+}
+
+func TestFakeErrors(t *testing.T) {
+
+	failed := errors.New("connection lost")
+
+	n := connect(t, gommander.NewFakeNode("node1", &gommander.FakeTransport{
+		Script:  map[string]gommander.FakeResult{"lost": {Err: failed}},
+		Default: gommander.FakeResult{Delay: time.Minute},
+	}))[0]
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Run("lost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results.Responses[0]; r.Err != failed {
+		t.Errorf("expected the scripted error, got %v", r.Err)
+	}
+
+	// A command with a delay runs until signalled
+	responses := make(chan gommander.Response, 1)
+	job, err := n.Execute(gommander.Request{
+		Command: "sleep",
+		Respond: func(r gommander.Response) error {
+			responses <- r
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for job.Signal("TERM") == gommander.ErrNotRunning {
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case r := <-responses:
+		if r.Signal != "TERM" || r.ExitCode != 143 {
+			t.Errorf("expected the command to be terminated, got exit %d, signal %q", r.ExitCode, r.Signal)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not terminated")
+	}
+}
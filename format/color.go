@@ -12,14 +12,14 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package main
+package format
 
 import (
-	"bytes"
-	"container/list"
-	"fmt"
+	"hash/fnv"
+	"sync"
 )
 
+// ANSI color codes.
 const (
 	Color_Off string = "\033[0m"
 
@@ -87,109 +87,49 @@ const (
 	On_IWhite  string = "\033[0;107m"
 )
 
-func color(s string, c string) string {
-	return fmt.Sprintf("%s%s\033[0m", c, s)
+// Palette of colors assigned to keys by ColorMap.
+var palette = []string{
+	Red, Green, Yellow, Blue, Purple, Cyan, White,
+	BBlack, BRed, BGreen, BYellow, BBlue, BPurple, BCyan, BWhite,
+	URed, UGreen, UYellow, UBlue, UPurple, UCyan, UWhite,
+	IRed, IGreen, IYellow, IBlue, IPurple, ICyan, IWhite,
+	BIRed, BIGreen, BIYellow, BIBlue, BIPurple, BICyan, BIWhite,
 }
 
-func column(s string, w int) string {
-	o := bytes.Buffer{}
-	i := 0
-	for ; i < len(s); i++ {
-		o.WriteRune(rune(s[i]))
-		if i >= w {
-			break
-		}
-	}
-	for j := i; j < w; j++ {
-		o.WriteRune(' ')
-	}
-	return o.String()
+// Wrap s in the color c.
+func Color(s string, c string) string {
+	return c + s + Color_Off
 }
 
+// ColorMap assigns each key, such as a host, a color. The color of a key
+// is derived from the key alone, so is stable between runs.
 type ColorMap struct {
+	mu      sync.Mutex
 	mapping map[string]string
-	colors  *list.List
 }
 
+// Create a new ColorMap.
 func NewColorMap() *ColorMap {
-	colors := list.New()
-	// colors.PushBack(Black)
-	colors.PushBack(Red)
-	colors.PushBack(Green)
-	colors.PushBack(Yellow)
-	colors.PushBack(Blue)
-	colors.PushBack(Purple)
-	colors.PushBack(Cyan)
-	colors.PushBack(White)
-	colors.PushBack(BBlack)
-	colors.PushBack(BRed)
-	colors.PushBack(BGreen)
-	colors.PushBack(BYellow)
-	colors.PushBack(BBlue)
-	colors.PushBack(BPurple)
-	colors.PushBack(BCyan)
-	colors.PushBack(BWhite)
-	colors.PushBack(UBlack)
-	colors.PushBack(URed)
-	colors.PushBack(UGreen)
-	colors.PushBack(UYellow)
-	colors.PushBack(UBlue)
-	colors.PushBack(UPurple)
-	colors.PushBack(UCyan)
-	colors.PushBack(UWhite)
-	// colors.PushBack(On_Black)
-	// colors.PushBack(On_Red)
-	// colors.PushBack(On_Green)
-	// colors.PushBack(On_Yellow)
-	// colors.PushBack(On_Blue)
-	// colors.PushBack(On_Purple)
-	// colors.PushBack(On_Cyan)
-	// colors.PushBack(On_White)
-	colors.PushBack(IBlack)
-	colors.PushBack(IRed)
-	colors.PushBack(IGreen)
-	colors.PushBack(IYellow)
-	colors.PushBack(IBlue)
-	colors.PushBack(IPurple)
-	colors.PushBack(ICyan)
-	colors.PushBack(IWhite)
-	colors.PushBack(BIBlack)
-	colors.PushBack(BIRed)
-	colors.PushBack(BIGreen)
-	colors.PushBack(BIYellow)
-	colors.PushBack(BIBlue)
-	colors.PushBack(BIPurple)
-	colors.PushBack(BICyan)
-	colors.PushBack(BIWhite)
-	// colors.PushBack(On_IBlack)
-	// colors.PushBack(On_IRed)
-	// colors.PushBack(On_IGreen)
-	// colors.PushBack(On_IYellow)
-	// colors.PushBack(On_IBlue)
-	// colors.PushBack(On_IPurple)
-	// colors.PushBack(On_ICyan)
-	// colors.PushBack(On_IWhite)
-
 	return &ColorMap{
 		mapping: map[string]string{},
-		colors:  colors,
 	}
 }
 
+// The color of a key.
 func (m *ColorMap) Get(key string) string {
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	color, ok := m.mapping[key]
 	if ok {
 		return color
 	}
 
-	elem := m.colors.Front()
-	if elem != nil {
-		m.colors.Remove(elem)
-		color = elem.Value.(string)
-		m.mapping[key] = color
-		return color
-	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
 
-	return Red
+	color = palette[h.Sum32()%uint32(len(palette))]
+	m.mapping[key] = color
+	return color
 }
@@ -0,0 +1,190 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package format renders the Responses of gommander operations as host
+// prefixed text, aligned tables, JSON or JSON Lines.
+//
+//	f := format.NewText(os.Stdout)
+//	f.Print(nodes.Run("uptime"))
+package format
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aerospike/gommander"
+)
+
+// ErrUnknownFormat is returned by New for an unknown format name.
+var ErrUnknownFormat = errors.New("Unknown format.")
+
+// Formatter writes Responses as they are received.
+type Formatter interface {
+
+	// Write each Response received from responses. The result is the
+	// first error writing, or otherwise err, so operations may be printed
+	// directly, as in Print(nodes.Run(command)).
+	Print(responses chan gommander.Response, err error) error
+}
+
+// Create a Formatter by name: "text", "table", "json" or "jsonl".
+func New(name string, w io.Writer) (Formatter, error) {
+	switch name {
+	case "text", "":
+		return NewText(w), nil
+	case "table":
+		return NewTable(w), nil
+	case "json":
+		return NewJSON(w), nil
+	case "jsonl":
+		return NewJSONLines(w), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Whether to write colors to w. Colors are written to terminals, unless
+// the NO_COLOR environment variable is set or TERM is "dumb".
+func ColorEnabled(w io.Writer) bool {
+
+	if len(os.Getenv("NO_COLOR")) > 0 || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Record is a Response, as written by the JSON and JSON Lines formatters.
+type Record struct {
+	Node      string    `json:"node"`
+	Command   string    `json:"command,omitempty"`
+	ExitCode  int       `json:"exit_code"`
+	Signal    string    `json:"signal,omitempty"`
+	Stdout    string    `json:"stdout"`
+	Stderr    string    `json:"stderr"`
+	Error     string    `json:"error,omitempty"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Duration  float64   `json:"duration"`
+	Attempts  int       `json:"attempts,omitempty"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// Create a Record of a Response. The Duration is in seconds.
+func NewRecord(r gommander.Response) Record {
+
+	rec := Record{
+		ExitCode:  r.ExitCode,
+		Command:   r.Command,
+		Signal:    r.Signal,
+		Started:   r.Started,
+		Finished:  r.Finished,
+		Duration:  r.Duration.Seconds(),
+		Attempts:  r.Attempts,
+		Truncated: r.Truncated,
+	}
+
+	if r.Node != nil {
+		rec.Node = r.Node.Name()
+	}
+
+	var err error
+	if rec.Stdout, err = read(r.StdoutReader); err == nil {
+		rec.Stderr, err = read(r.StderrReader)
+	}
+
+	switch {
+	case r.Err != nil:
+		rec.Error = r.Err.Error()
+	case err != nil:
+		rec.Error = err.Error()
+	}
+
+	return rec
+}
+
+// Read output of a Response, whether in memory or spooled to a file.
+func read(open func() (io.ReadCloser, error)) (string, error) {
+
+	rc, err := open()
+	if err != nil {
+		return "", err
+	}
+
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	return string(b), err
+}
+
+// The name of the Node of a Response.
+func name(r gommander.Response) string {
+	if r.Node == nil {
+		return ""
+	}
+	return r.Node.Name()
+}
+
+// Output of a Response, without surrounding blank lines and spaces.
+func trim(open func() (io.ReadCloser, error)) (string, error) {
+	out, err := read(open)
+	return strings.Trim(out, "\r\n "), err
+}
+
+// Fixed width column.
+func column(s string, w int) string {
+	if utf8.RuneCountInString(s) > w {
+		s = string([]rune(s)[:w])
+	}
+	return s + strings.Repeat(" ", w-utf8.RuneCountInString(s))
+}
+
+// A Writer which keeps the first error, so a Formatter writes on, and
+// reports it once done. The responses must still be drained.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return len(p), nil
+	}
+	n, err := e.w.Write(p)
+	if err != nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// The first error writing, or otherwise err.
+func (e *errWriter) result(err error) error {
+	if e.err != nil {
+		return e.err
+	}
+	return err
+}
@@ -0,0 +1,193 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/format"
+)
+
+// Run a command on two fake Nodes, spooling output when spool is set.
+func run(t *testing.T, spool bool) (chan gommander.Response, error) {
+
+	fake := &gommander.FakeTransport{
+		Script: map[string]gommander.FakeResult{
+			"cmd": {Stdout: "line1\nline2\n", Stderr: "warning\n", ExitCode: 2},
+		},
+	}
+
+	nodes := gommander.NodeList{
+		gommander.NewFakeNode("node2", fake),
+		gommander.NewFakeNode("node1", &gommander.FakeTransport{Script: fake.Script}),
+	}
+	if err := nodes.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nodes.Close() })
+
+	return nodes.Execute(gommander.Request{
+		Command:  "cmd",
+		Spool:    spool,
+		SpoolDir: t.TempDir(),
+	})
+}
+
+func TestText(t *testing.T) {
+
+	for _, spool := range []bool{false, true} {
+
+		var out bytes.Buffer
+		f := format.NewText(&out)
+		f.TimeLayout = ""
+
+		if err := f.Print(run(t, spool)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, line := range []string{
+			"node1            OUT  2   ]  line1\n",
+			"node1            OUT  2   ]  line2\n",
+			"node1            ERR  2   ]  warning\n",
+			"node2            OUT  2   ]  line1\n",
+		} {
+			if !strings.Contains(out.String(), line) {
+				t.Errorf("spool %v: missing %q in:\n%s", spool, line, out.String())
+			}
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+
+	for _, spool := range []bool{false, true} {
+
+		var out bytes.Buffer
+		if err := format.NewTable(&out).Print(run(t, spool)); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+		if len(lines) != 7 {
+			t.Fatalf("spool %v: expected a header and 3 rows per Node, got:\n%s", spool, out.String())
+		}
+
+		if !strings.HasPrefix(lines[0], "NODE") || !strings.HasPrefix(lines[1], "node1") || !strings.HasPrefix(lines[4], "node2") {
+			t.Errorf("spool %v: expected rows sorted by Node, got:\n%s", spool, out.String())
+		}
+
+		if !strings.HasSuffix(lines[3], "warning") {
+			t.Errorf("spool %v: expected stderr of a failed command, got %q", spool, lines[3])
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+
+	for _, spool := range []bool{false, true} {
+
+		var out bytes.Buffer
+		if err := format.NewJSON(&out).Print(run(t, spool)); err != nil {
+			t.Fatal(err)
+		}
+
+		var records []format.Record
+		if err := json.Unmarshal(out.Bytes(), &records); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 2 {
+			t.Fatalf("spool %v: expected 2 records, got %d", spool, len(records))
+		}
+
+		for _, rec := range records {
+			if rec.Stdout != "line1\nline2\n" || rec.Stderr != "warning\n" || rec.ExitCode != 2 || len(rec.Error) > 0 {
+				t.Errorf("spool %v: unexpected record %+v", spool, rec)
+			}
+		}
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+
+	var out bytes.Buffer
+	if err := format.NewJSONLines(&out).Print(run(t, true)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a line per Response, got:\n%s", out.String())
+	}
+
+	for _, line := range lines {
+		var rec format.Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Stdout != "line1\nline2\n" {
+			t.Errorf("unexpected record %+v", rec)
+		}
+	}
+}
+
+func TestSpoolRemoved(t *testing.T) {
+
+	responses, err := run(t, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Output which can no longer be read is reported as an error
+	removed := make(chan gommander.Response)
+	go func() {
+		for r := range responses {
+			os.Remove(r.StdoutFile)
+			removed <- r
+		}
+		close(removed)
+	}()
+
+	var out bytes.Buffer
+	if err := format.NewJSONLines(&out).Print(removed, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var rec format.Record
+	if err := json.Unmarshal([]byte(strings.SplitN(out.String(), "\n", 2)[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Error) == 0 {
+		t.Errorf("expected an error for removed output, got %+v", rec)
+	}
+}
+
+func TestNew(t *testing.T) {
+
+	for _, name := range []string{"", "text", "table", "json", "jsonl"} {
+		if _, err := format.New(name, os.Stdout); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	if _, err := format.New("xml", os.Stdout); err != format.ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
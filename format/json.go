@@ -0,0 +1,93 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/aerospike/gommander"
+)
+
+// JSON writes the Responses as an array of Records, once all are received.
+type JSON struct {
+	w io.Writer
+
+	// Indent the JSON
+	Indent bool
+}
+
+// Create a JSON formatter writing to w.
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{w: w, Indent: true}
+}
+
+// Write the Responses as an array of Records.
+func (f *JSON) Print(responses chan gommander.Response, err error) error {
+
+	if responses == nil {
+		return err
+	}
+
+	records := []Record{}
+	for r := range responses {
+		records = append(records, NewRecord(r))
+	}
+
+	encoder := json.NewEncoder(f.w)
+	if f.Indent {
+		encoder.SetIndent("", "  ")
+	}
+
+	if werr := encoder.Encode(records); werr != nil {
+		return werr
+	}
+
+	return err
+}
+
+// JSONLines writes each Response as a Record on its own line, as it is
+// received.
+type JSONLines struct {
+	w io.Writer
+}
+
+// Create a JSON Lines formatter writing to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// Write each Response as a line of JSON.
+func (f *JSONLines) Print(responses chan gommander.Response, err error) error {
+
+	if responses == nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f.w)
+
+	var werr error
+	for r := range responses {
+		if e := encoder.Encode(NewRecord(r)); e != nil && werr == nil {
+			werr = e
+		}
+	}
+
+	if werr != nil {
+		return werr
+	}
+
+	return err
+}
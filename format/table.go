@@ -0,0 +1,150 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aerospike/gommander"
+)
+
+// Table writes the Responses as a table, once all are received, sorted by
+// host. Each line of output is a row, with the stderr of failed commands
+// following their stdout.
+type Table struct {
+	w *errWriter
+
+	// Write ANSI colors. Defaults to ColorEnabled for the Writer.
+	Color bool
+
+	// Colors of hosts
+	Colors *ColorMap
+}
+
+// Create a Table formatter writing to w.
+func NewTable(w io.Writer) *Table {
+	return &Table{
+		w:      &errWriter{w: w},
+		Color:  ColorEnabled(w),
+		Colors: NewColorMap(),
+	}
+}
+
+type cell struct {
+	text  string
+	color string
+}
+
+// Write the Responses as a table.
+func (t *Table) Print(responses chan gommander.Response, err error) error {
+
+	if responses == nil {
+		return err
+	}
+
+	var all []gommander.Response
+	for r := range responses {
+		all = append(all, r)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return name(all[i]) < name(all[j])
+	})
+
+	rows := [][]cell{{{"NODE", ""}, {"EXIT", ""}, {"DURATION", ""}, {"OUTPUT", ""}}}
+
+	for _, r := range all {
+
+		host := name(r)
+		lines := []cell{}
+
+		stdout, err := trim(r.StdoutReader)
+		stderr, serr := trim(r.StderrReader)
+		if err == nil {
+			err = serr
+		}
+
+		switch {
+		case r.Err != nil:
+			lines = append(lines, cell{r.Err.Error(), BIRed})
+		case err != nil:
+			lines = append(lines, cell{err.Error(), BIRed})
+		default:
+			if len(stdout) > 0 {
+				for _, s := range strings.Split(stdout, "\n") {
+					lines = append(lines, cell{s, ""})
+				}
+			}
+			if len(stderr) > 0 && r.ExitCode != 0 {
+				for _, s := range strings.Split(stderr, "\n") {
+					lines = append(lines, cell{s, Red})
+				}
+			}
+		}
+
+		if len(lines) == 0 {
+			lines = append(lines, cell{})
+		}
+
+		exit := cell{fmt.Sprintf("%d", r.ExitCode), ""}
+		if r.ExitCode != 0 || r.Err != nil {
+			exit.color = BIRed
+		}
+
+		for i, line := range lines {
+			if i == 0 {
+				rows = append(rows, []cell{
+					{host, t.Colors.Get(host)},
+					exit,
+					{r.Duration.Round(time.Millisecond).String(), ""},
+					line,
+				})
+			} else {
+				rows = append(rows, []cell{{}, {}, {}, line})
+			}
+		}
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, c := range row {
+			if n := utf8.RuneCountInString(c.text); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	for _, row := range rows {
+		var b strings.Builder
+		for i, c := range row {
+			text := c.text
+			if t.Color && len(c.color) > 0 && len(text) > 0 {
+				text = Color(text, c.color)
+			}
+			b.WriteString(text)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c.text)+2))
+			}
+		}
+		fmt.Fprintln(t.w, strings.TrimRight(b.String(), " "))
+	}
+
+	return t.w.result(err)
+}
@@ -0,0 +1,125 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aerospike/gommander"
+)
+
+// Text writes each line of output prefixed with the start time, host,
+// stream and exit code of its Response. Each host is colored by a ColorMap.
+type Text struct {
+	w *errWriter
+
+	// Write ANSI colors. Defaults to ColorEnabled for the Writer.
+	Color bool
+
+	// Colors of hosts
+	Colors *ColorMap
+
+	// Width of the host column
+	HostWidth int
+
+	// Layout of the start time. If empty, the time is omitted.
+	TimeLayout string
+}
+
+// Create a Text formatter writing to w.
+func NewText(w io.Writer) *Text {
+	return &Text{
+		w:          &errWriter{w: w},
+		Color:      ColorEnabled(w),
+		Colors:     NewColorMap(),
+		HostWidth:  15,
+		TimeLayout: "2006-01-02 15:04:05 -0700",
+	}
+}
+
+// Write each line of output of the Responses.
+func (t *Text) Print(responses chan gommander.Response, err error) error {
+
+	if err != nil {
+		fmt.Fprintln(t.w, t.color(err.Error(), BIRed))
+	}
+
+	if responses == nil {
+		return t.w.result(err)
+	}
+
+	for r := range responses {
+		t.write(r)
+	}
+
+	return t.w.result(err)
+}
+
+func (t *Text) write(r gommander.Response) {
+
+	host := name(r)
+	exit := t.color(column(fmt.Sprintf("%d", r.ExitCode), 3), White)
+	sep := t.color("]", IBlack)
+
+	prefix := t.color(column(host, t.HostWidth), t.Colors.Get(host))
+	if len(t.TimeLayout) > 0 {
+		prefix = t.color(r.Started.Format(t.TimeLayout), IBlack) + "  " + prefix
+	}
+
+	opre := fmt.Sprintf("%s %s %s %s  ", prefix, t.color(column(" OUT", 5), IBlack), exit, sep)
+	epre := fmt.Sprintf("%s %s %s %s  ", prefix, t.color(column(" ERR", 5), BIRed), exit, sep)
+
+	if r.Err != nil {
+		fmt.Fprintln(t.w, epre+r.Err.Error())
+		return
+	}
+
+	stdout, err := trim(r.StdoutReader)
+	if err != nil {
+		fmt.Fprintln(t.w, epre+err.Error())
+		return
+	}
+
+	stderr, err := trim(r.StderrReader)
+	if err != nil {
+		fmt.Fprintln(t.w, epre+err.Error())
+		return
+	}
+
+	if len(stdout) > 0 {
+		for _, s := range strings.Split(stdout, "\n") {
+			fmt.Fprintln(t.w, opre+s)
+		}
+	} else if r.ExitCode == 0 {
+		fmt.Fprintln(t.w, opre+"<SUCCESS>")
+	}
+
+	if len(stderr) > 0 {
+		for _, s := range strings.Split(stderr, "\n") {
+			fmt.Fprintln(t.w, epre+s)
+		}
+	} else if r.ExitCode != 0 {
+		fmt.Fprintln(t.w, epre+"<FAILURE>")
+	}
+}
+
+func (t *Text) color(s string, c string) string {
+	if !t.Color {
+		return s
+	}
+	return Color(s, c)
+}
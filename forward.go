@@ -0,0 +1,266 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrNotSSH is returned by operations which need an SSH connection,
+// on a Node connected with another Transport.
+var ErrNotSSH = errors.New("Not connected over SSH.")
+
+// Forward relays connections accepted by a listener, on one side of a
+// Node's SSH connection, to an address dialled on the other side.
+type Forward struct {
+	node     *Node
+	listener net.Listener
+	dial     func(conn net.Conn) (net.Conn, error)
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Forward connections to a local address, to an address dialled from the
+// Node. The networks are "tcp" or "unix". For example, to reach a port
+// which only listens on the loopback interface of the Node:
+//
+//	n.ForwardLocal("tcp", "127.0.0.1:0", "tcp", "127.0.0.1:3003")
+func (n *Node) ForwardLocal(localNet, localAddr, remoteNet, remoteAddr string) (*Forward, error) {
+
+	if _, err := n.sshClient(); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen(localNet, localAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dial over the current connection, which is replaced on reconnecting
+	return n.forward(listener, func(net.Conn) (net.Conn, error) {
+		client, err := n.sshClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.Dial(remoteNet, remoteAddr)
+	}), nil
+}
+
+// Forward connections to an address on the Node, to a local address.
+// The networks are "tcp" or "unix". The Node listens over the current
+// connection, so the Forward closes if the connection is lost.
+func (n *Node) ForwardRemote(remoteNet, remoteAddr, localNet, localAddr string) (*Forward, error) {
+
+	client, err := n.sshClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var listener net.Listener
+	if remoteNet == "unix" {
+		listener, err = client.ListenUnix(remoteAddr)
+	} else {
+		listener, err = client.Listen(remoteNet, remoteAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return n.forward(listener, func(net.Conn) (net.Conn, error) {
+		return net.Dial(localNet, localAddr)
+	}), nil
+}
+
+// Forward a local port to a TCP address dialled from each Node in the
+// NodeList. Each Node is allocated its own port on the loopback interface,
+// which is found with Forward.Addr. If any forward fails, those already
+// opened are closed.
+func (l NodeList) ForwardLocal(remoteAddr string) (map[*Node]*Forward, error) {
+
+	forwards := map[*Node]*Forward{}
+
+	for _, n := range l {
+		f, err := n.ForwardLocal("tcp", "127.0.0.1:0", "tcp", remoteAddr)
+		if err != nil {
+			for _, f := range forwards {
+				f.Close()
+			}
+			return nil, &NodeError{Node: n, Err: err}
+		}
+		forwards[n] = f
+	}
+
+	return forwards, nil
+}
+
+// Address the Forward is listening on.
+func (f *Forward) Addr() net.Addr {
+	return f.listener.Addr()
+}
+
+// Stop listening, and close the forwarded connections.
+func (f *Forward) Close() error {
+	err := f.stop()
+	f.wg.Wait()
+	return err
+}
+
+// Stop listening, close the forwarded connections, and release the
+// Forward from the Node, unless already stopped.
+func (f *Forward) stop() error {
+
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	for c := range f.conns {
+		c.Close()
+	}
+	f.mu.Unlock()
+
+	f.node.unclose(f)
+
+	return f.listener.Close()
+}
+
+// Start a Forward, which is closed along with the Node.
+// The dial function is given each accepted connection, for protocols
+// where the client chooses the address to dial.
+func (n *Node) forward(listener net.Listener, dial func(net.Conn) (net.Conn, error)) *Forward {
+
+	f := &Forward{
+		node:     n,
+		listener: listener,
+		dial:     dial,
+		conns:    map[net.Conn]bool{},
+	}
+
+	n.closeWith(f)
+
+	f.wg.Add(1)
+	go f.serve()
+
+	return f
+}
+
+// Accept connections until closed, or the listener fails.
+func (f *Forward) serve() {
+
+	defer f.wg.Done()
+
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			f.stop()
+			return
+		}
+
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			f.relay(conn)
+		}()
+	}
+}
+
+// Relay a connection to the other side.
+func (f *Forward) relay(conn net.Conn) {
+
+	defer conn.Close()
+
+	if !f.track(conn) {
+		return
+	}
+	defer f.untrack(conn)
+
+	remote, err := f.dial(conn)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	if !f.track(remote) {
+		return
+	}
+	defer f.untrack(remote)
+
+	splice(conn, remote)
+}
+
+func (f *Forward) track(conns ...net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	for _, c := range conns {
+		f.conns[c] = true
+	}
+	return true
+}
+
+func (f *Forward) untrack(conns ...net.Conn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range conns {
+		delete(f.conns, c)
+	}
+}
+
+// Copy between two connections in both directions, until both
+// directions have finished.
+func splice(a, b net.Conn) {
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	half := func(dst, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			cw.CloseWrite()
+		} else {
+			dst.Close()
+		}
+	}
+
+	go half(a, b)
+	go half(b, a)
+
+	wg.Wait()
+}
+
+// The SSH client of the Node, for operations which need SSH.
+func (n *Node) sshClient() (*ssh.Client, error) {
+	t, ok := n.transport().(*SSHTransport)
+	if !ok {
+		return nil, ErrNotSSH
+	}
+	client := t.Client()
+	if client == nil {
+		return nil, ErrNotSSH
+	}
+	return client, nil
+}
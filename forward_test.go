@@ -0,0 +1,166 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+// Start a TCP server echoing what is written to it.
+func echoServer(t *testing.T) net.Listener {
+
+	t.Helper()
+
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { echo.Close() })
+
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return echo
+}
+
+// Expect a connection to echo what is written to it.
+func expectEcho(t *testing.T, conn net.Conn) {
+
+	t.Helper()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "ping" {
+		t.Fatalf("expected the connection to be relayed, got %q, %v", reply, err)
+	}
+}
+
+func TestForwardLocal(t *testing.T) {
+
+	echo := echoServer(t)
+	n := serve(t, gommandertest.Config{Forwarding: true}).Node("test", nil)
+	connect(t, n)
+
+	forward, err := n.ForwardLocal("tcp", "127.0.0.1:0", "tcp", echo.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", forward.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	expectEcho(t, conn)
+
+	// Closing the Forward closes its connections
+	if err := forward.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("expected the relayed connection to be closed")
+	}
+	if conn, err := net.Dial("tcp", forward.Addr().String()); err == nil {
+		conn.Close()
+		t.Error("expected the listener to be closed")
+	}
+
+	// A closed Forward is no longer held by the Node
+	if c := n.Closers(); c != 0 {
+		t.Errorf("expected the Forward to be released, %d still held", c)
+	}
+}
+
+func TestForwardReconnect(t *testing.T) {
+
+	echo := echoServer(t)
+	server := serve(t, gommandertest.Config{Forwarding: true})
+	n := connect(t, server.Node("test", nil))[0]
+
+	forward, err := n.ForwardLocal("tcp", "127.0.0.1:0", "tcp", echo.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconnect(t, server, n)
+
+	// The Forward dials over the new connection
+	conn, err := net.Dial("tcp", forward.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	expectEcho(t, conn)
+}
+
+func TestForwardClosedWithNode(t *testing.T) {
+
+	echo := echoServer(t)
+	n := serve(t, gommandertest.Config{Forwarding: true}).Node("test", nil)
+	connect(t, n)
+
+	forwards, err := gommander.NodeList{n}.ForwardLocal(echo.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", forwards[n].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEcho(t, conn)
+	conn.Close()
+
+	n.Close()
+
+	if conn, err := net.Dial("tcp", forwards[n].Addr().String()); err == nil {
+		conn.Close()
+		t.Errorf("expected %s to be closed with the Node", forwards[n].Addr())
+	}
+}
+
+func TestForwardNotSSH(t *testing.T) {
+
+	n := connect(t, gommander.NewLocalNode("local"))[0]
+
+	_, err := gommander.NodeList{n}.ForwardLocal("127.0.0.1:22")
+
+	var nodeErr *gommander.NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.Node != n {
+		t.Errorf("expected a NodeError for a Node without an SSH connection, got %v", err)
+	}
+}
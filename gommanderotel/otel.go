@@ -0,0 +1,102 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gommanderotel adapts an OpenTelemetry Tracer to a gommander
+// Tracer, so NodeList operations are exported with any OpenTelemetry
+// exporter, such as tracetest.InMemoryExporter.
+//
+//	exporter := tracetest.NewInMemoryExporter()
+//	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+//	nodes.SetTracer(gommanderotel.New(provider.Tracer("gommander")))
+package gommanderotel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer starts gommander Spans as OpenTelemetry spans.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// Create a Tracer starting spans with the given OpenTelemetry Tracer.
+func New(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// Start an OpenTelemetry span, as a child of the span in ctx if any.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...gommander.Attribute) (context.Context, gommander.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+	return ctx, &Span{span}
+}
+
+// Span wraps an OpenTelemetry span.
+type Span struct {
+	span trace.Span
+}
+
+// Add attributes to the span.
+func (s *Span) SetAttributes(attrs ...gommander.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+// Record an error on the span, and set its status to Error.
+func (s *Span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End the span.
+func (s *Span) End() {
+	s.span.End()
+}
+
+// The OpenTelemetry span.
+func (s *Span) Span() trace.Span {
+	return s.span
+}
+
+// Convert gommander Attributes to OpenTelemetry attributes.
+func convert(attrs []gommander.Attribute) []attribute.KeyValue {
+
+	kvs := make([]attribute.KeyValue, len(attrs))
+
+	for i, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs[i] = attribute.String(a.Key, v)
+		case bool:
+			kvs[i] = attribute.Bool(a.Key, v)
+		case int:
+			kvs[i] = attribute.Int(a.Key, v)
+		case int64:
+			kvs[i] = attribute.Int64(a.Key, v)
+		case float64:
+			kvs[i] = attribute.Float64(a.Key, v)
+		case time.Duration:
+			kvs[i] = attribute.Float64(a.Key, v.Seconds())
+		default:
+			kvs[i] = attribute.String(a.Key, fmt.Sprint(v))
+		}
+	}
+
+	return kvs
+}
@@ -0,0 +1,103 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommanderotel_test
+
+import (
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommanderotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(t.Context())
+
+	nodes := gommander.NodeList{
+		gommander.NewFakeNode("node1", &gommander.FakeTransport{
+			Default: gommander.FakeResult{ExitCode: 3},
+		}),
+		gommander.NewFakeNode("node2", &gommander.FakeTransport{}),
+	}
+	nodes.SetTracer(gommanderotel.New(provider.Tracer("gommander")))
+
+	if err := nodes[0].Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer nodes[0].Close()
+
+	// node2 is not connected, so the operation fails on it
+	responses, err := nodes.Run("cmd")
+	if err == nil {
+		t.Fatal("expected an error for the disconnected Node")
+	}
+	for range responses {
+	}
+
+	spans := exporter.GetSpans()
+
+	var root *tracetest.SpanStub
+	for i := range spans {
+		if spans[i].Name == "gommander.Run" {
+			root = &spans[i]
+		}
+	}
+	if root == nil {
+		t.Fatalf("missing the operation span in %d spans", len(spans))
+	}
+
+	if !hasAttribute(root.Attributes, attribute.Int("nodes", 2)) {
+		t.Errorf("expected the node count on the operation span, got %v", root.Attributes)
+	}
+
+	var commands, failed int
+	for _, s := range spans {
+		switch s.Name {
+		case "gommander.Run", "gommander.connect":
+		default:
+			if s.SpanContext.TraceID() != root.SpanContext.TraceID() {
+				t.Errorf("span %s is not part of the operation trace", s.Name)
+			}
+		}
+		if s.Name == "gommander.command" {
+			commands++
+			if !hasAttribute(s.Attributes, attribute.Int("exit_code", 3)) {
+				t.Errorf("expected the exit code on the command span, got %v", s.Attributes)
+			}
+		}
+		if s.Name == "gommander.node" && s.Status.Code == codes.Error {
+			failed++
+		}
+	}
+
+	if commands != 1 || failed != 1 {
+		t.Errorf("expected 1 command span and 1 failed node span, got %d and %d", commands, failed)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, kv attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == kv {
+			return true
+		}
+	}
+	return false
+}
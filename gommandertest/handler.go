@@ -0,0 +1,159 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommandertest
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Session is a command received by a test server.
+type Session struct {
+
+	// Command to execute
+	Command string
+
+	// Environment variables set by the client
+	Env map[string]string
+
+	// Whether a Pty was requested, and its terminal type.
+	// The Pty is simulated: Stderr writes to the same stream as Stdout,
+	// but the command is not given a terminal.
+	Pty  bool
+	Term string
+
+	// Streams of the command
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Signals sent by the client
+	Signals <-chan ssh.Signal
+}
+
+// Handler executes the command of a Session, returning its exit status,
+// or the signal which terminated it.
+type Handler func(s *Session) (status int, signal ssh.Signal)
+
+// Result is the scripted result of a command.
+type Result struct {
+
+	// Output of the command
+	Stdout string
+	Stderr string
+
+	// Exit status of the command
+	ExitCode int
+
+	// Time the command takes to run
+	Delay time.Duration
+}
+
+// Script creates a Handler which responds to commands with scripted
+// results. Commands missing from the script exit with 127.
+// A signal received during the Delay of a command terminates it.
+func Script(results map[string]Result) Handler {
+	return func(s *Session) (int, ssh.Signal) {
+
+		go io.Copy(ioutil.Discard, s.Stdin)
+
+		res, ok := results[s.Command]
+		if !ok {
+			io.WriteString(s.Stderr, "command not found\n")
+			return 127, ""
+		}
+
+		select {
+		case <-time.After(res.Delay):
+		case sig := <-s.Signals:
+			return 0, sig
+		}
+
+		io.WriteString(s.Stdout, res.Stdout)
+		io.WriteString(s.Stderr, res.Stderr)
+		return res.ExitCode, ""
+	}
+}
+
+// Signals delivered to commands run by Shell.
+var shellSignals = map[ssh.Signal]syscall.Signal{
+	ssh.SIGHUP:  syscall.SIGHUP,
+	ssh.SIGINT:  syscall.SIGINT,
+	ssh.SIGKILL: syscall.SIGKILL,
+	ssh.SIGQUIT: syscall.SIGQUIT,
+	ssh.SIGTERM: syscall.SIGTERM,
+}
+
+// Shell is a Handler which executes commands with the local shell,
+// as the user running the test.
+func Shell(s *Session) (int, ssh.Signal) {
+
+	cmd := exec.Command("/bin/sh", "-c", s.Command)
+	cmd.Env = os.Environ()
+	for k, v := range s.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdin = s.Stdin
+	cmd.Stdout = s.Stdout
+	cmd.Stderr = s.Stderr
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Start(); err != nil {
+		io.WriteString(s.Stderr, err.Error()+"\n")
+		return 127, ""
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case sig := <-s.Signals:
+				if signal, ok := shellSignals[sig]; ok {
+					cmd.Process.Signal(signal)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	if err == nil {
+		return 0, ""
+	}
+
+	exit, ok := err.(*exec.ExitError)
+	if !ok {
+		return 255, ""
+	}
+
+	if status, ok := exit.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		for name, sig := range shellSignals {
+			if sig == status.Signal() {
+				return 0, name
+			}
+		}
+	}
+
+	return exit.ExitCode(), ""
+}
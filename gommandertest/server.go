@@ -0,0 +1,511 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gommandertest provides in-process SSH servers, for testing code
+// built on gommander without real hosts.
+package gommandertest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Config describes the behaviour of a test server.
+type Config struct {
+
+	// Host key of the server. A key is generated if nil.
+	HostKey ssh.Signer
+
+	// Passwords accepted, by user
+	Passwords map[string]string
+
+	// Public keys accepted for any user
+	AuthorizedKeys []ssh.PublicKey
+
+	// Handler executing commands. Defaults to Shell.
+	Handler Handler
+
+	// Refuse environment variables, as servers do for names missing
+	// from their AcceptEnv setting
+	RejectEnv bool
+
+	// Serve the SFTP subsystem, from the local filesystem
+	SFTP bool
+
+	// Allow clients to dial TCP and Unix addresses through the server
+	Forwarding bool
+
+	// Delay before each session is opened and each command executed
+	Latency time.Duration
+
+	// Drop the connection without a response, for commands where it
+	// returns true
+	Fail func(command string) bool
+}
+
+// Server is an SSH server listening on the loopback interface.
+// Clients are allowed no authentication, unless Passwords or
+// AuthorizedKeys are configured.
+type Server struct {
+	config   Config
+	ssh      *ssh.ServerConfig
+	listener net.Listener
+	hostKey  ssh.PublicKey
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	commands []string
+	closed   bool
+}
+
+// Start a server with the given configuration.
+func NewServer(config Config) (*Server, error) {
+
+	if config.HostKey == nil {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		config.HostKey = signer
+	}
+
+	if config.Handler == nil {
+		config.Handler = Shell
+	}
+
+	s := &Server{
+		config:  config,
+		hostKey: config.HostKey.PublicKey(),
+		conns:   map[net.Conn]bool{},
+	}
+
+	s.ssh = &ssh.ServerConfig{
+		NoClientAuth: len(config.Passwords) == 0 && len(config.AuthorizedKeys) == 0,
+	}
+
+	if len(config.Passwords) > 0 {
+		s.ssh.PasswordCallback = s.checkPassword
+	}
+
+	if len(config.AuthorizedKeys) > 0 {
+		s.ssh.PublicKeyCallback = s.checkPublicKey
+	}
+
+	s.ssh.AddHostKey(config.HostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s.listener = listener
+
+	go s.serve()
+
+	return s, nil
+}
+
+// Address of the server.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Port of the server.
+func (s *Server) Port() uint {
+	return uint(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// Public host key of the server.
+func (s *Server) HostKey() ssh.PublicKey {
+	return s.hostKey
+}
+
+// Commands executed by the server, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Create a Node for the server, which verifies its host key.
+func (s *Server) Node(user string, auth []ssh.AuthMethod) *gommander.Node {
+	n := gommander.NewNode("127.0.0.1", s.Port(), user, auth)
+	n.HostKey = ssh.FixedHostKey(s.hostKey)
+	return n
+}
+
+// Drop the connections of the server, as if the network failed, while
+// still accepting new ones.
+func (s *Server) Drop() {
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+}
+
+// Stop the server, and drop its connections.
+func (s *Server) Close() error {
+
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	return s.listener.Close()
+}
+
+func (s *Server) checkPassword(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	if expected, ok := s.config.Passwords[meta.User()]; ok && expected == string(password) {
+		return nil, nil
+	}
+	return nil, errors.New("Invalid password.")
+}
+
+func (s *Server) checkPublicKey(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	for _, k := range s.config.AuthorizedKeys {
+		if string(k.Marshal()) == string(key.Marshal()) {
+			return nil, nil
+		}
+	}
+	return nil, errors.New("Unauthorized key.")
+}
+
+// Accept connections until closed.
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, s.ssh)
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+
+	go ssh.DiscardRequests(reqs)
+
+	for nc := range chans {
+		switch {
+		case nc.ChannelType() == "session":
+		case s.config.Forwarding && nc.ChannelType() == "direct-tcpip":
+			go s.handleDial(nc, "tcp")
+			continue
+		case s.config.Forwarding && nc.ChannelType() == "direct-streamlocal@openssh.com":
+			go s.handleDial(nc, "unix")
+			continue
+		default:
+			nc.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+
+		time.Sleep(s.config.Latency)
+
+		channel, requests, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+
+		go s.handleSession(conn, channel, requests)
+	}
+}
+
+// Payloads of session requests, as defined by RFC 4254.
+type execMsg struct {
+	Command string
+}
+
+type envMsg struct {
+	Name  string
+	Value string
+}
+
+type ptyMsg struct {
+	Term     string
+	Columns  uint32
+	Rows     uint32
+	Width    uint32
+	Height   uint32
+	Modelist string
+}
+
+type directTCPMsg struct {
+	Host       string
+	Port       uint32
+	OriginHost string
+	OriginPort uint32
+}
+
+type directUnixMsg struct {
+	Path     string
+	Reserved string
+	Port     uint32
+}
+
+type signalMsg struct {
+	Signal string
+}
+
+type exitSignalMsg struct {
+	Signal     string
+	CoreDumped bool
+	Error      string
+	Lang       string
+}
+
+// Dial an address for the client, and relay the channel to it.
+func (s *Server) handleDial(nc ssh.NewChannel, network string) {
+
+	var addr string
+
+	if network == "unix" {
+		var msg directUnixMsg
+		if err := ssh.Unmarshal(nc.ExtraData(), &msg); err != nil {
+			nc.Reject(ssh.ConnectionFailed, err.Error())
+			return
+		}
+		addr = msg.Path
+	} else {
+		var msg directTCPMsg
+		if err := ssh.Unmarshal(nc.ExtraData(), &msg); err != nil {
+			nc.Reject(ssh.ConnectionFailed, err.Error())
+			return
+		}
+		addr = net.JoinHostPort(msg.Host, strconv.Itoa(int(msg.Port)))
+	}
+
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		nc.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	channel, requests, err := nc.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	go ssh.DiscardRequests(requests)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(conn, channel)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(channel, conn)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func (s *Server) handleSession(conn net.Conn, channel ssh.Channel, requests <-chan *ssh.Request) {
+
+	defer channel.Close()
+
+	session := &Session{
+		Env:    map[string]string{},
+		Stdin:  channel,
+		Stdout: channel,
+		Stderr: channel.Stderr(),
+	}
+
+	signals := make(chan ssh.Signal, 8)
+	session.Signals = signals
+
+	for req := range requests {
+		switch req.Type {
+		case "env":
+			var msg envMsg
+			if s.config.RejectEnv || ssh.Unmarshal(req.Payload, &msg) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			session.Env[msg.Name] = msg.Value
+			req.Reply(true, nil)
+
+		case "pty-req":
+			var msg ptyMsg
+			if ssh.Unmarshal(req.Payload, &msg) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			session.Pty = true
+			session.Term = msg.Term
+			session.Stderr = channel
+			req.Reply(true, nil)
+
+		case "window-change":
+			req.Reply(true, nil)
+
+		case "signal":
+			var msg signalMsg
+			if ssh.Unmarshal(req.Payload, &msg) == nil {
+				select {
+				case signals <- ssh.Signal(msg.Signal):
+				default:
+				}
+			}
+			req.Reply(true, nil)
+
+		case "exec":
+			var msg execMsg
+			if ssh.Unmarshal(req.Payload, &msg) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+
+			s.mu.Lock()
+			s.commands = append(s.commands, msg.Command)
+			s.mu.Unlock()
+
+			if s.config.Fail != nil && s.config.Fail(msg.Command) {
+				conn.Close()
+				return
+			}
+
+			req.Reply(true, nil)
+			session.Command = msg.Command
+			go s.run(channel, session, requests)
+
+		case "subsystem":
+			var msg execMsg
+			if !s.config.SFTP || ssh.Unmarshal(req.Payload, &msg) != nil || msg.Command != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+
+			server, err := sftp.NewServer(channel)
+			if err != nil {
+				return
+			}
+			server.Serve()
+			server.Close()
+			return
+
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// Run the command of a session, and report how it exited.
+func (s *Server) run(channel ssh.Channel, session *Session, requests <-chan *ssh.Request) {
+
+	time.Sleep(s.config.Latency)
+
+	status, signal := s.config.Handler(session)
+
+	if len(signal) > 0 {
+		channel.SendRequest("exit-signal", false, ssh.Marshal(&exitSignalMsg{Signal: string(signal)}))
+	} else {
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, uint32(status))
+		channel.SendRequest("exit-status", false, buf)
+	}
+
+	channel.Close()
+}
+
+// Cluster is a set of test servers.
+type Cluster []*Server
+
+// Start a number of servers with the same configuration.
+func NewCluster(size int, config Config) (Cluster, error) {
+
+	c := Cluster{}
+
+	for i := 0; i < size; i++ {
+		s, err := NewServer(config)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c = append(c, s)
+	}
+
+	return c, nil
+}
+
+// Create a NodeList for the servers, and connect to them.
+func (c Cluster) Connect(user string, auth []ssh.AuthMethod) (gommander.NodeList, error) {
+
+	nodes := gommander.NodeList{}
+	for _, s := range c {
+		nodes = append(nodes, s.Node(user, auth))
+	}
+
+	if err := nodes.Connect(); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// Stop the servers.
+func (c Cluster) Close() error {
+
+	var first error
+
+	for _, s := range c {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
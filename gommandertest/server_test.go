@@ -0,0 +1,273 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommandertest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestClusterScript(t *testing.T) {
+
+	cluster, err := gommandertest.NewCluster(3, gommandertest.Config{
+		Handler: gommandertest.Script(map[string]gommandertest.Result{
+			"hostname": {Stdout: "node\n"},
+			"false":    {ExitCode: 1, Stderr: "failed\n"},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	nodes, err := cluster.Connect("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nodes.Close()
+
+	results, err := gommander.Collect(nodes.Run("hostname"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Responses) != 3 || len(results.Groups) != 1 {
+		t.Fatalf("expected 3 responses in 1 group, got %d in %d", len(results.Responses), len(results.Groups))
+	}
+	if out := results.Groups[0].Response.Stdout.String(); out != "node\n" {
+		t.Errorf("unexpected stdout %q", out)
+	}
+
+	results, err = gommander.Collect(nodes.Run("false"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := results.Summary(); s.Failed != 3 {
+		t.Errorf("expected 3 failures, got %+v", s)
+	}
+
+	results, err = gommander.Collect(nodes.Run("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results.Responses {
+		if r.ExitCode != 127 {
+			t.Errorf("expected exit 127 for an unscripted command, got %d", r.ExitCode)
+		}
+	}
+
+	for _, s := range cluster {
+		if commands := s.Commands(); len(commands) != 3 || commands[0] != "hostname" {
+			t.Errorf("unexpected commands %q", commands)
+		}
+	}
+}
+
+func TestPasswordAuth(t *testing.T) {
+
+	server, err := gommandertest.NewServer(gommandertest.Config{
+		Passwords: map[string]string{"test": "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	n := server.Node("test", []ssh.AuthMethod{ssh.Password("wrong")})
+	if err := n.Connect(); err == nil {
+		n.Close()
+		t.Fatal("expected a wrong password to be refused")
+	}
+
+	n = server.Node("test", []ssh.AuthMethod{ssh.Password("secret")})
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	n.Close()
+}
+
+func TestHostKeyMismatch(t *testing.T) {
+
+	cluster, err := gommandertest.NewCluster(2, gommandertest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	n := cluster[0].Node("test", nil)
+	n.HostKey = ssh.FixedHostKey(cluster[1].HostKey())
+
+	if err := n.Connect(); err == nil {
+		n.Close()
+		t.Fatal("expected the host key of another server to be refused")
+	}
+}
+
+func TestShell(t *testing.T) {
+
+	server, err := gommandertest.NewServer(gommandertest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	n := server.Node("test", nil)
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: "cat; echo err >&2; exit 3",
+		Stdin:   []byte("in\n"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := results.Responses[0]
+	if r.ExitCode != 3 || r.Stdout.String() != "in\n" || r.Stderr.String() != "err\n" {
+		t.Errorf("unexpected response: exit %d, stdout %q, stderr %q", r.ExitCode, r.Stdout, r.Stderr)
+	}
+}
+
+func TestLatencyAndFail(t *testing.T) {
+
+	server, err := gommandertest.NewServer(gommandertest.Config{
+		Handler: gommandertest.Script(map[string]gommandertest.Result{"true": {}}),
+		Latency: 50 * time.Millisecond,
+		Fail: func(command string) bool {
+			return command == "crash"
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	n := server.Node("test", nil)
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Run("true"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := results.Responses[0].Duration; d < 50*time.Millisecond {
+		t.Errorf("expected latency of at least 50ms, took %s", d)
+	}
+
+	results, err = gommander.Collect(gommander.NodeList{n}.Run("crash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Responses[0].Err == nil {
+		t.Error("expected an error when the connection is dropped")
+	}
+}
+
+func TestSFTP(t *testing.T) {
+
+	server, err := gommandertest.NewServer(gommandertest.Config{SFTP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	n := server.Node("test", nil)
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	dest := filepath.Join(t.TempDir(), "file")
+
+	results, err := gommander.Collect(gommander.NodeList{n}.WriteBytes(dest, []byte("content")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results.Responses[0]; r.Err != nil || r.ExitCode != 0 {
+		t.Fatalf("write failed: exit %d, %v", r.ExitCode, r.Err)
+	}
+
+	content, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func TestSignal(t *testing.T) {
+
+	server, err := gommandertest.NewServer(gommandertest.Config{
+		Handler: gommandertest.Script(map[string]gommandertest.Result{
+			"sleep": {Delay: time.Minute},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	n := server.Node("test", nil)
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	responses := make(chan gommander.Response, 1)
+	job, err := n.Execute(gommander.Request{
+		Command: "sleep",
+		Respond: func(r gommander.Response) error {
+			responses <- r
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The signal is only delivered once the command is running
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Signal(ssh.SIGTERM) == gommander.ErrNotRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("job never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case r := <-responses:
+		if r.Signal != string(ssh.SIGTERM) {
+			t.Errorf("expected SIGTERM, got signal %q, error %v", r.Signal, r.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not terminated")
+	}
+
+	if !strings.Contains(strings.Join(server.Commands(), "\n"), "sleep") {
+		t.Errorf("unexpected commands %q", server.Commands())
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+// Nodes which were never connected, and so fail to receive Requests.
+func disconnected(hosts ...string) gommander.NodeList {
+	var l gommander.NodeList
+	for _, host := range hosts {
+		l = append(l, &gommander.Node{Host: host})
+	}
+	return l
+}
+
+// Connect the Nodes, closing them once the test completes.
+func connect(t *testing.T, nodes ...*gommander.Node) gommander.NodeList {
+
+	t.Helper()
+
+	l := gommander.NodeList(nodes)
+	if err := l.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	return l
+}
+
+// Connected fake Nodes, node1 onwards, each answering every command
+// with its FakeResult.
+func fakes(t *testing.T, results ...gommander.FakeResult) gommander.NodeList {
+
+	t.Helper()
+
+	var nodes []*gommander.Node
+	for i, res := range results {
+		nodes = append(nodes, gommander.NewFakeNode("node"+strconv.Itoa(i+1), &gommander.FakeTransport{Default: res}))
+	}
+
+	return connect(t, nodes...)
+}
+
+// Start a test server, stopping it once the test completes.
+func serve(t *testing.T, config gommandertest.Config) *gommandertest.Server {
+
+	t.Helper()
+
+	server, err := gommandertest.NewServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return server
+}
+
+// Drop the connections of the server, and reconnect the Node once it has
+// noticed.
+func reconnect(t *testing.T, server *gommandertest.Server, n *gommander.Node) {
+
+	t.Helper()
+
+	server.Drop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for n.State() != gommander.Disconnected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the Node to be disconnected, is %s", n.State())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+}
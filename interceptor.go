@@ -0,0 +1,78 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bytes"
+)
+
+// Handler executes a Request, returning its Response.
+type Handler func(req *Request) Response
+
+// Interceptor wraps the execution of Requests on a Node. It may modify
+// the Request before calling next, return its own Response without
+// calling next, or modify the Response returned by next.
+type Interceptor func(n *Node, req *Request, next Handler) Response
+
+// Add interceptors to the Node. The first interceptor added is the
+// outermost, seeing Requests first and Responses last.
+func (n *Node) Use(interceptors ...Interceptor) {
+	n.mu.Lock()
+	n.interceptors = append(n.interceptors, interceptors...)
+	n.mu.Unlock()
+}
+
+// Add interceptors to each Node in the NodeList.
+func (l NodeList) Use(interceptors ...Interceptor) {
+	for _, n := range l {
+		n.Use(interceptors...)
+	}
+}
+
+// Execute a Job through the interceptors of the Node.
+func (n *Node) intercept(job *Job) Response {
+
+	n.mu.Lock()
+	interceptors := n.interceptors
+	n.mu.Unlock()
+
+	handler := func(req *Request) Response {
+		job.Request = *req
+		return n.attempt(job)
+	}
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(req *Request) Response {
+			return interceptor(n, req, next)
+		}
+	}
+
+	req := job.Request
+	res := handler(&req)
+
+	// Complete Responses made by interceptors
+	if res.Node == nil {
+		res.Node = n
+	}
+	if res.Stdout == nil {
+		res.Stdout = new(bytes.Buffer)
+	}
+	if res.Stderr == nil {
+		res.Stderr = new(bytes.Buffer)
+	}
+
+	return res
+}
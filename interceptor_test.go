@@ -0,0 +1,82 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestInterceptors(t *testing.T) {
+
+	fake := &gommander.FakeTransport{
+		Handler: func(n *gommander.Node, command string, stdin []byte) gommander.FakeResult {
+			return gommander.FakeResult{Stdout: command}
+		},
+	}
+	n := gommander.NewFakeNode("node1", fake)
+
+	var order []string
+
+	trace := func(name string) gommander.Interceptor {
+		return func(n *gommander.Node, req *gommander.Request, next gommander.Handler) gommander.Response {
+			order = append(order, name+" in")
+			res := next(req)
+			order = append(order, name+" out")
+			return res
+		}
+	}
+
+	n.Use(trace("outer"), trace("inner"))
+
+	// Rewrite Requests, and short-circuit forbidden ones
+	n.Use(func(n *gommander.Node, req *gommander.Request, next gommander.Handler) gommander.Response {
+		if strings.HasPrefix(req.Command, "rm ") {
+			return gommander.Response{ExitCode: 1, Stderr: bytes.NewBufferString("forbidden")}
+		}
+		req.Command = "nice " + req.Command
+		return next(req)
+	})
+
+	connect(t, n)
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Run("make"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := results.Responses[0].Stdout.String(); out != "nice make" {
+		t.Errorf("expected the rewritten command to run, got %q", out)
+	}
+
+	if got := strings.Join(order, ", "); got != "outer in, inner in, inner out, outer out" {
+		t.Errorf("unexpected order: %s", got)
+	}
+
+	results, err = gommander.Collect(gommander.NodeList{n}.Run("rm -rf /"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := results.Responses[0]
+	if r.ExitCode != 1 || r.Stderr.String() != "forbidden" || r.Node != n || r.Stdout == nil {
+		t.Errorf("expected the completed Response of the interceptor, got %+v", r)
+	}
+	if commands := fake.Commands(); len(commands) != 1 {
+		t.Errorf("expected the forbidden command not to run, got %q", commands)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrCancelled is the Response error for a Job cancelled before it started.
+var ErrCancelled = errors.New("Cancelled.")
+
+// ErrNotRunning is returned when signalling a Job which is not running.
+var ErrNotRunning = errors.New("Not running.")
+
+// CancelTimeout is how long Cancel waits for a killed command to exit,
+// before closing its session.
+var CancelTimeout = 5 * time.Second
+
+// SignalTimeout is how long Signal waits for a command sent a terminating
+// signal to exit, before closing its session. Zero, the default, leaves
+// the session open, so a command which handles the signal keeps running.
+var SignalTimeout time.Duration
+
+// Signals which terminate a command, unless it handles them.
+var terminating = map[ssh.Signal]bool{
+	ssh.SIGHUP:  true,
+	ssh.SIGINT:  true,
+	ssh.SIGQUIT: true,
+	ssh.SIGTERM: true,
+	ssh.SIGKILL: true,
+}
+
+// Job is a handle to a Request executed by a Node.
+type Job struct {
+
+	// Node executing the Request
+	Node *Node
+
+	// Request being executed
+	Request Request
+
+	queued    time.Time
+	mu        sync.Mutex
+	session   Session
+	cancelled bool
+	cancel    chan struct{}
+	done      chan struct{}
+}
+
+func newJob(n *Node, req Request) *Job {
+	return &Job{
+		Node:    n,
+		Request: req,
+		queued:  time.Now(),
+		cancel:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Done returns a channel which is closed once the Job has completed,
+// and its Response has been sent.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait for the Job to complete.
+func (j *Job) Wait() {
+	<-j.done
+}
+
+// Send a signal to the remote process.
+// Some servers ignore signal requests, so if SignalTimeout is set, and
+// SIGHUP, SIGINT, SIGQUIT, SIGTERM or SIGKILL has not ended the command
+// within it, the session is closed, as for Cancel.
+func (j *Job) Signal(sig ssh.Signal) error {
+
+	j.mu.Lock()
+	session := j.session
+	j.mu.Unlock()
+
+	if session == nil {
+		return ErrNotRunning
+	}
+
+	timeout := time.Duration(0)
+	if terminating[sig] {
+		timeout = SignalTimeout
+	}
+
+	return j.signal(session, sig, timeout)
+}
+
+// Cancel the Job. A Job which has not started yet is skipped, and
+// responds with ErrCancelled. A running Job is sent SIGKILL.
+// Some servers ignore signal requests, so if the command has not exited
+// within CancelTimeout, the session is closed. This hangs up commands
+// running with a Pty, and stops waiting on any other command.
+func (j *Job) Cancel() error {
+
+	j.mu.Lock()
+	session := j.session
+	if !j.cancelled {
+		j.cancelled = true
+		close(j.cancel)
+	}
+	j.mu.Unlock()
+
+	if session == nil {
+		return nil
+	}
+
+	return j.signal(session, ssh.SIGKILL, CancelTimeout)
+}
+
+// Signal the session, closing it if the command has not exited within
+// the timeout, unless it is zero.
+func (j *Job) signal(session Session, sig ssh.Signal, timeout time.Duration) error {
+
+	err := session.Signal(sig)
+
+	if timeout > 0 {
+		go func() {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			select {
+			case <-j.done:
+			case <-timer.C:
+				session.Close()
+			}
+		}()
+	}
+
+	return err
+}
+
+// Attach the session running the Job.
+// Returns false if the Job has been cancelled.
+func (j *Job) attach(session Session) bool {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.cancelled {
+		return false
+	}
+
+	j.session = session
+	return true
+}
+
+// Detach the session once the command has completed.
+func (j *Job) detach() {
+	j.mu.Lock()
+	j.session = nil
+	j.mu.Unlock()
+}
+
+// Whether the Job has been cancelled.
+func (j *Job) isCancelled() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.cancelled
+}
+
+// Sleep for the duration, returning false if the Job is cancelled first.
+func (j *Job) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-j.cancel:
+		return false
+	}
+}
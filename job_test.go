@@ -0,0 +1,183 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestExecuteNotListening(t *testing.T) {
+
+	n := &gommander.Node{Host: "node1"}
+
+	if _, err := n.Execute(gommander.Request{Command: "true"}); err == nil {
+		t.Error("expected a Node which is not connected to refuse the Request")
+	}
+
+	// Nothing is running to signal or cancel
+	if err := n.Signal("TERM"); err != nil {
+		t.Error(err)
+	}
+	if err := n.Cancel(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCancelQueued(t *testing.T) {
+
+	n := &gommander.Node{Host: "node1"}
+	job := gommander.NewJob(n, gommander.Request{Command: "true"})
+
+	if err := job.Signal("TERM"); err != gommander.ErrNotRunning {
+		t.Errorf("expected ErrNotRunning, got %v", err)
+	}
+
+	if err := job.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A Job cancelled before it started is never run
+	if job.Attach(nil) {
+		t.Error("expected the cancelled Job not to start")
+	}
+}
+
+// Start a command, returning its Job once running, and its Responses.
+func start(t *testing.T, n *gommander.Node, command string) (*gommander.Job, chan gommander.Response) {
+
+	t.Helper()
+
+	responses := make(chan gommander.Response, 1)
+
+	job, err := n.Execute(gommander.Request{
+		Command: command,
+		Respond: func(r gommander.Response) error {
+			responses <- r
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signals are only delivered once the command is running, so probe
+	// with one which has no effect
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Signal("CONT") == gommander.ErrNotRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("job never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return job, responses
+}
+
+// Wait for the Response of a Job.
+func response(t *testing.T, responses chan gommander.Response) gommander.Response {
+
+	t.Helper()
+
+	select {
+	case r := <-responses:
+		return r
+	case <-time.After(10 * time.Second):
+		t.Fatal("no response")
+		return gommander.Response{}
+	}
+}
+
+func TestCancel(t *testing.T) {
+
+	n := connect(t, serve(t, gommandertest.Config{}).Node("test", nil))[0]
+
+	job, responses := start(t, n, "sleep 60")
+
+	sent := time.Now()
+	if err := job.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := response(t, responses); r.Signal != string(ssh.SIGKILL) {
+		t.Errorf("expected the command to be killed, got signal %q, error %v", r.Signal, r.Err)
+	}
+	if d := time.Since(sent); d > 5*time.Second {
+		t.Errorf("command was not killed promptly, took %s", d)
+	}
+}
+
+func TestSignalHandled(t *testing.T) {
+
+	// A server whose commands clean up after SIGTERM
+	server := serve(t, gommandertest.Config{
+		Handler: func(s *gommandertest.Session) (int, ssh.Signal) {
+			<-s.Signals
+			time.Sleep(200 * time.Millisecond)
+			return 3, ""
+		},
+	})
+	n := connect(t, server.Node("test", nil))[0]
+
+	if gommander.SignalTimeout != 0 {
+		t.Fatalf("expected closing after a signal to be opt-in, got %s", gommander.SignalTimeout)
+	}
+
+	job, responses := start(t, n, "cleanup")
+
+	if err := job.Signal(ssh.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session is left open until the command exits
+	if r := response(t, responses); r.Err != nil || r.ExitCode != 3 {
+		t.Errorf("expected the command to exit after cleaning up, got exit %d, error %v", r.ExitCode, r.Err)
+	}
+}
+
+func TestSignalTimeout(t *testing.T) {
+
+	// A server which ignores signal requests
+	server := serve(t, gommandertest.Config{
+		Handler: func(s *gommandertest.Session) (int, ssh.Signal) {
+			time.Sleep(time.Minute)
+			return 0, ""
+		},
+	})
+	n := connect(t, server.Node("test", nil))[0]
+
+	timeout := gommander.SignalTimeout
+	gommander.SignalTimeout = 100 * time.Millisecond
+	defer func() { gommander.SignalTimeout = timeout }()
+
+	job, responses := start(t, n, "ignore")
+
+	sent := time.Now()
+	if err := job.Signal(ssh.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	r := response(t, responses)
+	if r.Err == nil && r.ExitCode == 0 {
+		t.Error("expected the closed session to fail the command")
+	}
+	if d := time.Since(sent); d > 5*time.Second {
+		t.Errorf("session was not closed after the timeout, took %s", d)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// LocalTransport executes commands on the controller, with a shell.
+// A single LocalTransport may be shared by many Nodes.
+type LocalTransport struct {
+
+	// Shell to execute commands with. Defaults to "/bin/sh".
+	Shell string
+}
+
+// Create a Node which executes commands on the controller.
+func NewLocalNode(name string) *Node {
+	return &Node{
+		Host:      name,
+		Transport: &LocalTransport{},
+	}
+}
+
+// Connect does nothing, as there is nothing to connect to.
+func (t *LocalTransport) Connect(n *Node) error {
+	return nil
+}
+
+// Open a session for a local process.
+func (t *LocalTransport) NewSession() (Session, error) {
+
+	shell := t.Shell
+	if len(shell) == 0 {
+		shell = "/bin/sh"
+	}
+
+	return &localSession{shell: shell}, nil
+}
+
+// Close does nothing, as there is nothing to disconnect from.
+func (t *LocalTransport) Close() error {
+	return nil
+}
+
+// Signals which can be delivered to local processes.
+var localSignals = map[ssh.Signal]os.Signal{
+	ssh.SIGABRT: syscall.SIGABRT,
+	ssh.SIGALRM: syscall.SIGALRM,
+	ssh.SIGFPE:  syscall.SIGFPE,
+	ssh.SIGHUP:  syscall.SIGHUP,
+	ssh.SIGILL:  syscall.SIGILL,
+	ssh.SIGINT:  syscall.SIGINT,
+	ssh.SIGKILL: syscall.SIGKILL,
+	ssh.SIGPIPE: syscall.SIGPIPE,
+	ssh.SIGQUIT: syscall.SIGQUIT,
+	ssh.SIGSEGV: syscall.SIGSEGV,
+	ssh.SIGTERM: syscall.SIGTERM,
+}
+
+type localSession struct {
+	mu     sync.Mutex
+	shell  string
+	env    []string
+	cmd    *exec.Cmd
+	exited bool
+	stdin  io.Reader
+	pipe   io.WriteCloser
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (s *localSession) Setenv(name, value string) error {
+	s.env = append(s.env, name+"="+value)
+	return nil
+}
+
+func (s *localSession) RequestPty(term string, height, width int, modes ssh.TerminalModes) error {
+	return errors.New("Pty not supported by local transport.")
+}
+
+func (s *localSession) WindowChange(height, width int) error {
+	return errors.New("Pty not supported by local transport.")
+}
+
+func (s *localSession) Signal(sig ssh.Signal) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd == nil || s.cmd.Process == nil {
+		return ErrNotRunning
+	}
+
+	signal, ok := localSignals[sig]
+	if !ok {
+		return errors.New("Unsupported signal: " + string(sig))
+	}
+
+	return s.cmd.Process.Signal(signal)
+}
+
+func (s *localSession) StdinPipe() (io.WriteCloser, error) {
+	if s.stdin != nil {
+		return nil, errors.New("Stdin already set.")
+	}
+	r, w := io.Pipe()
+	s.stdin = r
+	s.pipe = w
+	return w, nil
+}
+
+func (s *localSession) SetOutput(stdout, stderr io.Writer) {
+	s.stdout = stdout
+	s.stderr = stderr
+}
+
+func (s *localSession) Start(command string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != nil {
+		return errors.New("Already started.")
+	}
+
+	cmd := exec.Command(s.shell, "-c", command)
+	cmd.Env = append(os.Environ(), s.env...)
+	cmd.Stdin = s.stdin
+	cmd.Stdout = s.stdout
+	cmd.Stderr = s.stderr
+
+	// Children of a killed shell may hold its output open
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	s.cmd = cmd
+	return nil
+}
+
+func (s *localSession) Wait() error {
+
+	s.mu.Lock()
+	cmd := s.cmd
+	s.mu.Unlock()
+
+	if cmd == nil {
+		return errors.New("Not started.")
+	}
+
+	err := cmd.Wait()
+
+	s.mu.Lock()
+	s.exited = true
+	s.mu.Unlock()
+
+	// Unblock the copy of stdin, if the command did not read it all
+	if s.pipe != nil {
+		s.pipe.Close()
+	}
+
+	exit, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+
+	// Report signals as the SSH server would
+	if status, ok := exit.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		for name, sig := range localSignals {
+			if sig == status.Signal() {
+				return NewExitError(128+int(status.Signal()), string(name))
+			}
+		}
+	}
+
+	return NewExitError(exit.ExitCode(), "")
+}
+
+func (s *localSession) Close() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != nil && !s.exited {
+		return s.cmd.Process.Kill()
+	}
+	return nil
+}
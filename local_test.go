@@ -0,0 +1,99 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestLocal(t *testing.T) {
+
+	n := connect(t, gommander.NewLocalNode("local"))[0]
+	dir := t.TempDir()
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: `printf '%s|' "$GREETING"; pwd; cat; echo err >&2; exit 3`,
+		Env:     map[string]string{"GREETING": "it's me"},
+		Dir:     dir,
+		Stdin:   []byte("input"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := results.Responses[0]
+	if r.Err != nil || r.ExitCode != 3 {
+		t.Fatalf("expected exit 3, got %d, %v", r.ExitCode, r.Err)
+	}
+	if expected := "it's me|" + dir + "\ninput"; r.Stdout.String() != expected {
+		t.Errorf("expected stdout %q, got %q", expected, r.Stdout)
+	}
+	if r.Stderr.String() != "err\n" {
+		t.Errorf("unexpected stderr %q", r.Stderr)
+	}
+
+	// A Pty cannot be allocated for a local process
+	results, err = gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: "true",
+		Pty:     &gommander.Pty{},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Responses[0].Err == nil {
+		t.Error("expected a Pty to be refused")
+	}
+}
+
+func TestLocalCancel(t *testing.T) {
+
+	n := connect(t, gommander.NewLocalNode("local"))[0]
+
+	responses := make(chan gommander.Response, 1)
+	job, err := n.Execute(gommander.Request{
+		Command: "sleep 60",
+		Respond: func(r gommander.Response) error {
+			responses <- r
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for job.Signal("CONT") == gommander.ErrNotRunning {
+		time.Sleep(time.Millisecond)
+	}
+
+	sent := time.Now()
+	if err := job.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-responses:
+		if r.Signal != "KILL" {
+			t.Errorf("expected the process to be killed, got signal %q, error %v", r.Signal, r.Err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("process was not killed")
+	}
+	if d := time.Since(sent); d > 5*time.Second {
+		t.Errorf("process was not killed promptly, took %s", d)
+	}
+}
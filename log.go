@@ -0,0 +1,173 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"log/slog"
+	"regexp"
+	"sort"
+)
+
+// Value logged in place of secrets.
+const redactedValue = "[REDACTED]"
+
+// Matches names of environment variables which hold secrets.
+var secretName = regexp.MustCompile(`(?i)pass|secret|token|key|credential|auth`)
+
+// Matches names of command line secrets, whose words are separated by "_",
+// "-" or ".". Words ending in password, passwd, passphrase, secret or token
+// match, as do the whole words pass, pwd, key, apikey and credential, so
+// PGPASSWORD and SECRET_KEY match, but keyspace and monkey do not.
+const secretWords = `(?:[a-z0-9]+[_.-])*` +
+	`(?:[a-z0-9]*(?:password|passwd|passphrase|secret|token)s?|pass|pwd|keys?|apikey|credentials?)` +
+	`(?:[_.-][a-z0-9]+)*`
+
+// Matches secrets assigned in commands, such as PASSWORD=x or --token x.
+// A quoted value is matched whole, capturing its quote.
+var secretAssignment = regexp.MustCompile(`(?i)((?:^|\s)-{1,2}` + secretWords + `(?:=|\s+)|\b` + secretWords + `=)` +
+	`(?:(')[^']*'?|(")(?:[^"\\]|\\.)*"?|[^\s'";&|]+)`)
+
+// Redact secrets assigned in a command.
+func redactCommand(command string) string {
+	return secretAssignment.ReplaceAllString(command, "${1}${2}${3}"+redactedValue+"${2}${3}")
+}
+
+// Environment variables, with those which appear to hold secrets redacted.
+func redactEnv(env map[string]string) map[string]string {
+
+	redacted := make(map[string]string, len(env))
+	for k, v := range env {
+		if secretName.MatchString(k) {
+			v = redactedValue
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+// Set the Logger of each Node in the NodeList.
+func (l NodeList) SetLogger(logger *slog.Logger) {
+	for _, n := range l {
+		n.Logger = logger
+	}
+}
+
+// Logger for the Node, with the Node as an attribute, masking its Secrets.
+// If the Node has no Logger, records are discarded.
+func (n *Node) log() *slog.Logger {
+
+	if n.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	logger := n.Logger
+	if n.Secrets != nil {
+		logger = slog.New(&maskHandler{handler: logger.Handler(), secrets: n.Secrets})
+	}
+
+	return logger.With("node", n.Name())
+}
+
+// Log the start of a command. Stdin and the become password are never
+// logged, and secrets in the command and environment are redacted.
+func (n *Node) logStart(req *Request, esc *escalation) {
+
+	attrs := []any{"command", redactCommand(req.Command)}
+
+	if env := n.environ(req); len(env) > 0 {
+		attrs = append(attrs, "env", logEnv(env))
+	}
+	if dir := n.workdir(req); len(dir) > 0 {
+		attrs = append(attrs, "dir", dir)
+	}
+	if req.Pty != nil {
+		attrs = append(attrs, "pty", true)
+	}
+	if esc != nil {
+		attrs = append(attrs, "become", esc.become)
+	}
+	attrs = append(attrs, "stdin_bytes", len(req.Stdin))
+
+	n.log().Info("command started", attrs...)
+}
+
+// Log the completion of an attempt to execute a Request.
+func (n *Node) logFinish(req *Request, res *Response) {
+
+	attrs := []any{
+		"command", redactCommand(req.Command),
+		"attempt", res.Attempts,
+		"exit_code", res.ExitCode,
+		"duration", res.Duration,
+		"bytes_in", res.BytesIn,
+		"bytes_out", res.BytesOut,
+	}
+
+	if len(res.Signal) > 0 {
+		attrs = append(attrs, "signal", res.Signal)
+	}
+	if res.Truncated {
+		attrs = append(attrs, "truncated", true)
+	}
+
+	switch {
+	case res.Err != nil:
+		n.log().Error("command failed", append(attrs, "error", res.Err)...)
+	case res.ExitCode != 0:
+		n.log().Warn("command finished", attrs...)
+	default:
+		n.log().Info("command finished", attrs...)
+	}
+}
+
+// Log a file transfer to the Node.
+func (n *Node) logTransfer(dest string, size int) {
+	n.log().Info("transfer", "dest", dest, "bytes", size)
+}
+
+// Environment variables as a log group, with secrets redacted.
+func logEnv(env map[string]string) slog.Value {
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env = redactEnv(env)
+
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slog.String(k, env[k])
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue logs the Become without its password.
+func (b *Become) LogValue() slog.Value {
+
+	password := ""
+	if len(b.Password) > 0 {
+		password = redactedValue
+	}
+
+	return slog.GroupValue(
+		slog.String("method", b.Method),
+		slog.String("user", b.User),
+		slog.String("password", password),
+	)
+}
@@ -0,0 +1,104 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestRedactCommand(t *testing.T) {
+
+	cases := map[string]string{
+		"deploy --password=hunter2":     "deploy --password=[REDACTED]",
+		"deploy --api-token hunter2 -v": "deploy --api-token [REDACTED] -v",
+		"PGPASSWORD='hunter2' psql":     "PGPASSWORD='[REDACTED]' psql",
+		"export SECRET_KEY=hunter2; ls": "export SECRET_KEY=[REDACTED]; ls",
+		"ssh-keygen -t ed25519":         "ssh-keygen -t ed25519",
+		"echo hello":                    "echo hello",
+		"PGPASSWORD='a b c' psql":       "PGPASSWORD='[REDACTED]' psql",
+		`login --password "hunter 2"`:   `login --password "[REDACTED]"`,
+		`TOKEN="a \"b\" c" run`:         `TOKEN="[REDACTED]" run`,
+		"db.password=x app":             "db.password=[REDACTED] app",
+		"cqlsh --keyspace=test":         "cqlsh --keyspace=test",
+		"monkey=banana make":            "monkey=banana make",
+		"env API_KEY=a DB_PASS=b run":   "env API_KEY=[REDACTED] DB_PASS=[REDACTED] run",
+	}
+
+	for command, expected := range cases {
+		if redacted := gommander.RedactCommand(command); redacted != expected {
+			t.Errorf("redactCommand(%q) = %q, expected %q", command, redacted, expected)
+		}
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+
+	env := gommander.RedactEnv(map[string]string{
+		"API_TOKEN":   "a",
+		"DB_PASSWORD": "b",
+		"AUTH_HEADER": "c",
+		"MODE":        "prod",
+	})
+
+	for k, v := range env {
+		if (k == "MODE") != (v != gommander.RedactedValue) {
+			t.Errorf("unexpected %s=%s", k, v)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	n := gommander.NewFakeNode("node1", &gommander.FakeTransport{
+		Default: gommander.FakeResult{Stdout: "done", ExitCode: 1},
+	})
+	n.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	n.Env = map[string]string{"API_TOKEN": "abc", "MODE": "prod"}
+	n.Become = &gommander.Become{Password: "hunter2"}
+
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: "login --password=hunter2",
+		Stdin:   []byte("stdin-data"),
+	}))
+	n.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	for _, secret := range []string{"hunter2", "abc", "stdin-data"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q logged:\n%s", secret, out)
+		}
+	}
+
+	for _, expected := range []string{"connected", "command started", "command finished", "node=node1", "env.MODE=prod", "exit_code=1", "closed"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("missing %q in:\n%s", expected, out)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics records statistics of Nodes: connections, reconnects, commands
+// by exit code, their durations, and bytes transferred. Each statistic is
+// recorded with a "node" label, and names are prefixed "gommander_".
+type Metrics interface {
+
+	// Add to a counter.
+	Add(name string, delta float64, labels ...Label)
+
+	// Observe a value of a histogram.
+	Observe(name string, value float64, labels ...Label)
+}
+
+// Label of a statistic.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Default histogram buckets, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry is an in-memory Metrics, which serves its statistics over HTTP
+// in the Prometheus text format. It is also an expvar.Var, so may be
+// published with expvar.Publish.
+type Registry struct {
+
+	// Histogram buckets. Defaults to DefaultBuckets.
+	Buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Create a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Set the Metrics of each Node in the NodeList.
+func (l NodeList) SetMetrics(metrics Metrics) {
+	for _, n := range l {
+		n.Metrics = metrics
+	}
+}
+
+// Add to a counter.
+func (r *Registry) Add(name string, delta float64, labels ...Label) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counters == nil {
+		r.counters = map[string]map[string]float64{}
+	}
+	if r.counters[name] == nil {
+		r.counters[name] = map[string]float64{}
+	}
+
+	r.counters[name][formatLabels(labels)] += delta
+}
+
+// Observe a value of a histogram.
+func (r *Registry) Observe(name string, value float64, labels ...Label) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.histograms == nil {
+		r.histograms = map[string]map[string]*histogram{}
+	}
+	if r.histograms[name] == nil {
+		r.histograms[name] = map[string]*histogram{}
+	}
+
+	key := formatLabels(labels)
+
+	h := r.histograms[name][key]
+	if h == nil {
+		buckets := r.Buckets
+		if buckets == nil {
+			buckets = DefaultBuckets
+		}
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		r.histograms[name][key] = h
+	}
+
+	for i, le := range h.buckets {
+		if value <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Serve the statistics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WritePrometheus(w)
+}
+
+// Write the statistics in the Prometheus text format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := bufio.NewWriter(w)
+
+	for _, name := range sortedKeys(r.counters) {
+		fmt.Fprintf(b, "# TYPE %s counter\n", name)
+		for _, labels := range sortedKeys(r.counters[name]) {
+			fmt.Fprintf(b, "%s%s %s\n", name, braces(labels), formatFloat(r.counters[name][labels]))
+		}
+	}
+
+	for _, name := range sortedKeys(r.histograms) {
+		fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+		for _, labels := range sortedKeys(r.histograms[name]) {
+			h := r.histograms[name][labels]
+			for i, le := range h.buckets {
+				fmt.Fprintf(b, "%s_bucket%s %d\n", name, braces(join(labels, "le", formatFloat(le))), h.counts[i])
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", name, braces(join(labels, "le", "+Inf")), h.count)
+			fmt.Fprintf(b, "%s_sum%s %s\n", name, braces(labels), formatFloat(h.sum))
+			fmt.Fprintf(b, "%s_count%s %d\n", name, braces(labels), h.count)
+		}
+	}
+
+	return b.Flush()
+}
+
+// The statistics as JSON, for expvar. Counters are keyed by name then
+// labels, with histograms giving their count and sum.
+func (r *Registry) String() string {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := map[string]map[string]interface{}{}
+
+	for name, series := range r.counters {
+		stats[name] = map[string]interface{}{}
+		for labels, v := range series {
+			stats[name][labels] = v
+		}
+	}
+
+	for name, series := range r.histograms {
+		stats[name] = map[string]interface{}{}
+		for labels, h := range series {
+			stats[name][labels] = map[string]interface{}{"count": h.count, "sum": h.sum}
+		}
+	}
+
+	b, _ := json.Marshal(stats)
+	return string(b)
+}
+
+// Add to a counter for the Node.
+func (n *Node) count(name string, delta float64, labels ...Label) {
+	if n.Metrics != nil {
+		n.Metrics.Add("gommander_"+name, delta, append([]Label{{"node", n.Name()}}, labels...)...)
+	}
+}
+
+// Observe a duration for the Node, in seconds.
+func (n *Node) observe(name string, d time.Duration, labels ...Label) {
+	if n.Metrics != nil {
+		n.Metrics.Observe("gommander_"+name, d.Seconds(), append([]Label{{"node", n.Name()}}, labels...)...)
+	}
+}
+
+// Record the statistics of a Response.
+func (n *Node) measure(res Response) {
+
+	code := strconv.Itoa(res.ExitCode)
+	if res.Err != nil {
+		code = "error"
+	}
+
+	n.count("commands_total", 1, Label{"exit_code", code})
+	n.count("bytes_in_total", float64(res.BytesIn))
+	n.count("bytes_out_total", float64(res.BytesOut))
+
+	if res.Attempts > 1 {
+		n.count("retries_total", float64(res.Attempts-1))
+	}
+
+	if !res.Started.IsZero() {
+		n.observe("command_duration_seconds", res.Duration)
+	}
+}
+
+// Escapes label values in the Prometheus format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Labels in the Prometheus format, sorted by name.
+func formatLabels(labels []Label) string {
+
+	sorted := append([]Label(nil), labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	parts := make([]string, len(sorted))
+	for i, l := range sorted {
+		parts[i] = l.Name + `="` + labelEscaper.Replace(l.Value) + `"`
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Append a label to formatted labels.
+func join(labels, name, value string) string {
+	label := name + `="` + labelEscaper.Replace(value) + `"`
+	if len(labels) == 0 {
+		return label
+	}
+	return labels + "," + label
+}
+
+func braces(labels string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
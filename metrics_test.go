@@ -0,0 +1,92 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestPrometheusHistogram(t *testing.T) {
+
+	r := gommander.NewRegistry()
+	r.Buckets = []float64{0.1, 1}
+
+	r.Observe("latency_seconds", 0.05, gommander.Label{"node", "a"})
+	r.Observe("latency_seconds", 0.5, gommander.Label{"node", "a"})
+	r.Observe("latency_seconds", 5, gommander.Label{"node", "a"})
+	r.Add("commands_total", 2, gommander.Label{"node", "a"}, gommander.Label{"exit_code", "0"})
+
+	var out bytes.Buffer
+	if err := r.WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# TYPE commands_total counter
+commands_total{exit_code="0",node="a"} 2
+# TYPE latency_seconds histogram
+latency_seconds_bucket{node="a",le="0.1"} 1
+latency_seconds_bucket{node="a",le="1"} 2
+latency_seconds_bucket{node="a",le="+Inf"} 3
+latency_seconds_sum{node="a"} 5.55
+latency_seconds_count{node="a"} 3
+`
+
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+
+	r := gommander.NewRegistry()
+	r.Add("total", 1, gommander.Label{"node", "a\"b\\c\nd"})
+
+	var out bytes.Buffer
+	r.WritePrometheus(&out)
+
+	if !strings.Contains(out.String(), `total{node="a\"b\\c\nd"} 1`) {
+		t.Errorf("label not escaped:\n%s", out.String())
+	}
+}
+
+func TestNodeMetrics(t *testing.T) {
+
+	r := gommander.NewRegistry()
+	n := gommander.NewFakeNode("node1", &gommander.FakeTransport{
+		Script: map[string]gommander.FakeResult{"false": {ExitCode: 1}},
+	})
+	n.Metrics = r
+	connect(t, n)
+
+	if _, err := gommander.Collect(gommander.NodeList{n}.Run("false")); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	r.WritePrometheus(&out)
+
+	for _, line := range []string{
+		`gommander_commands_total{exit_code="1",node="node1"} 1`,
+		`gommander_command_duration_seconds_count{node="node1"} 1`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, out.String())
+		}
+	}
+}
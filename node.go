@@ -16,10 +16,13 @@ package gommander
 
 import (
 	"bytes"
-	"errors"
+	"context"
 	"io"
 	"io/ioutil"
-	"strconv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -39,10 +42,72 @@ type Node struct {
 	// Authentication Method
 	Auth []ssh.AuthMethod
 
-	// SSH Client
-	client *ssh.Client
+	// Host key verification, required to connect over SSH. To accept
+	// any host key, set it to ssh.InsecureIgnoreHostKey().
+	HostKey ssh.HostKeyCallback
 
-	requests chan Request
+	// Transport to execute commands over. Defaults to SSHTransport.
+	Transport Transport
+
+	// Pool to share SSH connections from, if any
+	Pool *Pool
+
+	// Logger for connections, sessions and commands. If nil, nothing
+	// is logged.
+	Logger *slog.Logger
+
+	// Tracer for connections and Requests. If nil, nothing is traced.
+	Tracer Tracer
+
+	// Metrics to record statistics of the Node to, if any
+	Metrics Metrics
+
+	// Audit to record Requests executed on the Node to, if any
+	Audit *Audit
+
+	// Secrets to mask in Responses, errors, logs and audit records, if any
+	Secrets *Secrets
+
+	// Environment variables applied to every Request on the node.
+	// These override variables of the same name in Request.Env.
+	Env map[string]string
+
+	// Working directory applied to every Request on the node.
+	// If set, this overrides Request.Dir.
+	Dir string
+
+	// Privilege escalation applied to Requests on the node which
+	// do not specify their own.
+	Become *Become
+
+	// Retry policy applied to Requests on the node which do not
+	// specify their own.
+	Retry *Retry
+
+	// Guards sending to requests against closing it
+	send     sync.RWMutex
+	requests chan *Job
+
+	mu sync.Mutex
+
+	// Job currently running
+	job *Job
+
+	// Lifecycle state, and the connection it refers to
+	state      State
+	generation int
+
+	// Whether the Node has ever connected
+	connected bool
+
+	// Event subscribers
+	hooks []Hooks
+
+	// Request interceptors
+	interceptors []Interceptor
+
+	// Resources to close with the connection
+	closers []io.Closer
 }
 
 // Request represents the command, stdin and callback responder
@@ -55,8 +120,43 @@ type Request struct {
 	// Stdin Buffer
 	Stdin []byte
 
+	// Environment variables for the command
+	Env map[string]string
+
+	// Working directory for the command
+	Dir string
+
+	// Pseudo-terminal to allocate for the command, if any
+	Pty *Pty
+
+	// Privilege escalation for the command, if any
+	Become *Become
+
+	// Maximum number of bytes of each of stdout and stderr to capture.
+	// The rest is discarded, and a truncation marker appended.
+	// Zero means no limit.
+	MaxOutput int64
+
+	// Spool stdout and stderr to temporary files, instead of memory.
+	// The output is read with Response.StdoutReader and StderrReader,
+	// and the files deleted with Response.Remove. Nodes with Secrets
+	// refuse to spool, returning ErrSpoolSecrets.
+	Spool bool
+
+	// Directory for spool files. Defaults to the system temporary directory.
+	SpoolDir string
+
+	// Retry policy for the command, if any
+	Retry *Retry
+
 	// Response Channel
 	Respond func(Response) error
+
+	// Context holding the Span of the operation the Request is part of
+	ctx context.Context
+
+	// Destination, if the Request transfers a file
+	transfer string
 }
 
 // Response represents the result of a command executed on a Node, including exit code,
@@ -74,6 +174,49 @@ type Response struct {
 
 	// Stderr Buffer
 	Stderr *bytes.Buffer
+
+	// Whether the command ran with a Pty, in which case stderr
+	// is merged into Stdout
+	Pty bool
+
+	// Error which prevented the command from running or completing,
+	// such as a failed session or ErrBecomeAuth
+	Err error
+
+	// Command actually executed, after applying the environment,
+	// working directory and privilege escalation
+	Command string
+
+	// Time the Request was submitted to the Node
+	Queued time.Time
+
+	// Time the command was started
+	Started time.Time
+
+	// Time the command completed
+	Finished time.Time
+
+	// Duration of the command, from start to completion
+	Duration time.Duration
+
+	// Number of bytes written to stdin
+	BytesIn int64
+
+	// Number of bytes read from stdout and stderr
+	BytesOut int64
+
+	// Signal which terminated the command, if any
+	Signal string
+
+	// Whether stdout or stderr was truncated by Request.MaxOutput
+	Truncated bool
+
+	// Files stdout and stderr were spooled to, if Request.Spool was set
+	StdoutFile string
+	StderrFile string
+
+	// Number of attempts made to execute the Request
+	Attempts int
 }
 
 // Create a new Node.
@@ -104,101 +247,442 @@ func Parsekey(file string) (private ssh.Signer, err error) {
 	return
 }
 
-// Connect to the node over SSH.
+// Connect to the node, over SSH unless another Transport is set.
 func (n *Node) Connect() error {
+	return n.connect(context.Background())
+}
+
+// Connect to the node, tracing the connection as a child of the Span
+// in ctx.
+func (n *Node) connect(ctx context.Context) (err error) {
 
-	config := &ssh.ClientConfig{
-		User: n.User,
-		Auth: n.Auth,
+	ctx, span := n.span(ctx, "connect")
+	defer func() { endSpan(span, err) }()
+
+	n.mu.Lock()
+	if n.state == Idle || n.state == Busy || n.state == Connecting {
+		n.mu.Unlock()
+		return ErrConnected
 	}
+	n.generation++
+	generation := n.generation
+	n.mu.Unlock()
 
-	client, err := ssh.Dial("tcp", n.Host+":"+strconv.Itoa(int(n.Port)), config)
+	n.setState(Connecting)
+	n.log().Debug("connecting")
+	start := time.Now()
+
+	n.mu.Lock()
+	if n.Transport == nil {
+		n.Transport = &SSHTransport{}
+	}
+	n.mu.Unlock()
+
+	// Release a connection which was lost
+	n.send.RLock()
+	lost := n.requests != nil
+	n.send.RUnlock()
+
+	if lost {
+		n.Transport.Close()
+	}
+
+	if c, ok := n.Transport.(contextConnector); ok {
+		err = c.ConnectContext(ctx, n)
+	} else {
+		err = n.Transport.Connect(n)
+	}
 
 	if err != nil {
+		n.log().Error("connect failed", "error", err, "duration", time.Since(start))
+		n.count("connect_errors_total", 1)
+		n.setState(Disconnected)
+		n.emitError(err)
 		return err
 	}
 
-	n.client = client
+	n.log().Info("connected", "duration", time.Since(start))
+	n.count("connections_total", 1)
+	n.observe("connect_duration_seconds", time.Since(start))
+
+	n.mu.Lock()
+	reconnect := n.connected
+	n.connected = true
+	n.mu.Unlock()
+
+	if reconnect {
+		n.count("reconnects_total", 1)
+	}
+
 	n.listen()
+	n.setState(Idle)
+	n.watchConnection(generation)
 
 	return nil
 }
 
-// Close the SSH connection.
+// Close the connection.
 func (n *Node) Close() error {
 
+	n.send.Lock()
 	if n.requests == nil {
-		return errors.New("Not listening.")
+		n.send.Unlock()
+		return ErrNotConnected
 	}
 
 	close(n.requests)
 	n.requests = nil
+	n.send.Unlock()
 
-	n.client.Close()
-	return nil
+	n.setState(Closed)
+	n.log().Info("closed")
+
+	n.mu.Lock()
+	closers := n.closers
+	n.closers = nil
+	n.mu.Unlock()
+
+	for _, c := range closers {
+		c.Close()
+	}
+
+	return n.Transport.Close()
 }
 
 // Execute a request against the Node.
 // This sends the request to the Node receive channel, for processing.
-func (n *Node) Execute(req Request) error {
-	n.requests <- req
+// The result is a Job, which can be used to signal or cancel the command.
+func (n *Node) Execute(req Request) (*Job, error) {
+
+	n.send.RLock()
+	defer n.send.RUnlock()
+
+	if n.requests == nil {
+		return nil, ErrNotConnected
+	}
+
+	if req.Spool && n.Secrets != nil {
+		return nil, ErrSpoolSecrets
+	}
+
+	job := newJob(n, req)
+	n.requests <- job
+	return job, nil
+}
+
+// Send a signal to the command running on the Node, if any.
+func (n *Node) Signal(sig ssh.Signal) error {
+	if job := n.running(); job != nil {
+		return job.Signal(sig)
+	}
 	return nil
 }
 
+// Cancel the command running on the Node, if any.
+func (n *Node) Cancel() error {
+	if job := n.running(); job != nil {
+		return job.Cancel()
+	}
+	return nil
+}
+
+// The Transport of the Node, which Connect may set concurrently.
+func (n *Node) transport() Transport {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.Transport
+}
+
+// Close a resource along with the connection.
+func (n *Node) closeWith(c io.Closer) {
+	n.mu.Lock()
+	n.closers = append(n.closers, c)
+	n.mu.Unlock()
+}
+
+// Stop closing a resource along with the connection, once it is closed.
+func (n *Node) unclose(c io.Closer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i, closer := range n.closers {
+		if closer == c {
+			n.closers = append(n.closers[:i], n.closers[i+1:]...)
+			return
+		}
+	}
+}
+
+// The Job currently running on the Node.
+func (n *Node) running() *Job {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.job
+}
+
 // Listens for Requests on the Node receive channel, then processes
 // the request and sends the Response to channel specific by the Request.
-func (n *Node) listen() error {
+func (n *Node) listen() {
+
+	requests := make(chan *Job)
 
+	n.send.Lock()
 	if n.requests != nil {
-		return errors.New("Already listening.")
+		close(n.requests)
 	}
-
-	n.requests = make(chan Request)
+	n.requests = requests
+	n.send.Unlock()
 
 	go func(n *Node) {
-		for req := range n.requests {
-			res := Response{
-				Node:     n,
-				ExitCode: 0,
-				Stdout:   new(bytes.Buffer),
-				Stderr:   new(bytes.Buffer),
-			}
-			n.execute(&req, &res)
-			req.Respond(res)
+		for job := range requests {
+
+			respond := job.Request.Respond
+
+			n.mu.Lock()
+			n.job = job
+			n.mu.Unlock()
+			n.busy(true)
+
+			res := n.intercept(job)
+			n.maskResponse(&job.Request, &res)
+
+			n.mu.Lock()
+			n.job = nil
+			n.mu.Unlock()
+			n.busy(false)
+
+			n.measure(res)
+			n.audit(&job.Request, res)
+			n.emitResponse(res)
+			respond(res)
+			close(job.done)
 		}
 	}(n)
-	return nil
 }
 
-// Execute a Request and populate the Response.
-func (n *Node) execute(req *Request, res *Response) error {
+// Move between Idle and Busy, unless the Node has been closed or
+// disconnected meanwhile.
+func (n *Node) busy(busy bool) {
+
+	from, to := Idle, Busy
+	if !busy {
+		from, to = Busy, Idle
+	}
+
+	n.mu.Lock()
+	ok := n.state == from
+	n.mu.Unlock()
+
+	if ok {
+		n.setState(to)
+	}
+}
+
+// Execute a Job, retrying according to its Retry policy, and return
+// the Response of the last attempt.
+func (n *Node) attempt(job *Job) Response {
+
+	policy := job.Request.Retry
+	if policy == nil {
+		policy = n.Retry
+	}
+
+	for attempt := 1; ; attempt++ {
+		res := Response{
+			Node:     n,
+			ExitCode: 0,
+			Stdout:   new(bytes.Buffer),
+			Stderr:   new(bytes.Buffer),
+			Queued:   job.queued,
+			Attempts: attempt,
+		}
+
+		ctx, span := n.span(job.Request.ctx, "attempt", Attribute{"attempt", attempt})
+
+		n.emitRequestStart(&job.Request)
+		res.Err = n.execute(ctx, job, &res)
+		n.logFinish(&job.Request, &res)
+
+		endResponse(span, res)
+
+		if policy == nil || attempt >= policy.Attempts || !policy.retryable(res) {
+			return res
+		}
+
+		if job.isCancelled() || !job.sleep(policy.delay(attempt)) {
+			return res
+		}
 
-	session, err := n.client.NewSession()
+		res.Remove()
+	}
+}
+
+// Execute a Job and populate the Response, tracing its phases as
+// children of the Span in ctx.
+func (n *Node) execute(ctx context.Context, job *Job, res *Response) error {
+
+	req := &job.Request
+
+	_, span := n.span(ctx, "session")
+	session, err := n.Transport.NewSession()
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
 
-	defer session.Close()
+	n.log().Debug("session opened")
 
-	session.Stdout = res.Stdout
-	session.Stderr = res.Stderr
+	defer func() {
+		session.Close()
+		n.log().Debug("session closed")
+	}()
 
-	go func() {
-		stdin, err := session.StdinPipe()
-		if err != nil {
-			return
+	if !job.attach(session) {
+		return ErrCancelled
+	}
+
+	defer job.detach()
+
+	esc, err := n.escalation(req)
+	if err != nil {
+		return err
+	}
+
+	command, err := n.prepare(session, req, esc == nil)
+	if err != nil {
+		return err
+	}
+
+	pty := req.Pty
+	if pty == nil && esc != nil && esc.needsPty() {
+		pty = &Pty{}
+	}
+
+	out, err := newOutput(n, req, res)
+	if err != nil {
+		return err
+	}
+
+	defer out.close(res)
+
+	var stdout, stderr io.Writer = out.stdout, out.stderr
+
+	if pty != nil {
+		if err = requestPty(session, pty); err != nil {
+			return err
 		}
+		res.Pty = true
+		stderr = nil
+	}
 
-		defer stdin.Close()
+	pipe, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	// Stdin may still be written after the command has exited
+	var written int64
+	stdin := &countWriter{w: pipe, n: &written}
+
+	if esc != nil {
+		command = esc.wrap(command)
+		abort := func() { session.Close() }
+		// The password is written to the pipe, so is not counted
+		if pty != nil {
+			stdout = esc.watch(stdout, pipe, abort)
+		} else {
+			stderr = esc.watch(stderr, pipe, abort)
+		}
+	}
 
+	if stdout != nil {
+		stdout = &countWriter{w: stdout, n: &res.BytesOut}
+	}
+	if stderr != nil {
+		stderr = &countWriter{w: stderr, n: &res.BytesOut}
+	}
+
+	session.SetOutput(stdout, stderr)
+
+	n.logStart(req, esc)
+
+	if len(req.transfer) > 0 {
+		_, span = n.span(ctx, "transfer",
+			Attribute{"dest", req.transfer}, Attribute{"bytes", len(req.Stdin)})
+	} else {
+		_, span = n.span(ctx, "command")
+	}
+
+	res.Command = command
+	if w, ok := session.(commandWrapper); ok {
+		res.Command = w.wrap(command)
+	}
+	res.Started = time.Now()
+
+	if err = session.Start(command); err != nil {
+		endSpan(span, err)
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer pipe.Close()
+		if esc != nil {
+			select {
+			case <-esc.ready:
+			case <-done:
+				return
+			}
+		}
 		io.Copy(stdin, bytes.NewReader(req.Stdin))
 	}()
 
-	if err = session.Run(req.Command); err != nil {
-		res.ExitCode = err.(*ssh.ExitError).ExitStatus()
+	if pty != nil && pty.Resize != nil {
+		go forwardResize(session, pty.Resize, done)
+	}
+
+	err = session.Wait()
+
+	if exit, ok := err.(exitStatus); ok {
+		span.SetAttributes(Attribute{"exit_code", exit.ExitStatus()})
+		endSpan(span, nil)
+	} else {
+		endSpan(span, err)
+	}
+
+	res.Finished = time.Now()
+	res.Duration = res.Finished.Sub(res.Started)
+	res.BytesIn = atomic.LoadInt64(&written)
+
+	if esc != nil {
+		if aerr := esc.finish(); aerr != nil {
+			return aerr
+		}
+	}
+
+	if err != nil {
+		exit, ok := err.(exitStatus)
+		if !ok {
+			return err
+		}
+		res.ExitCode = exit.ExitStatus()
+		res.Signal = exit.Signal()
 	} else {
 		res.ExitCode = 0
 	}
 
 	return nil
 }
+
+// A Writer which counts the bytes written through it.
+type countWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,52 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestCountWriter(t *testing.T) {
+
+	var (
+		stdout, stderr bytes.Buffer
+		count          int64
+		wg             sync.WaitGroup
+	)
+
+	// Stdout and stderr are counted together, as they are written
+	for _, w := range []io.Writer{
+		gommander.NewCountWriter(&stdout, &count),
+		gommander.NewCountWriter(&stderr, &count),
+	} {
+		wg.Add(1)
+		go func(w io.Writer) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				io.WriteString(w, "line\n")
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if count != 1000 || stdout.Len() != 500 || stderr.Len() != 500 {
+		t.Errorf("expected 1000 bytes counted, got %d", count)
+	}
+}
@@ -16,9 +16,12 @@ package gommander
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"sync"
+
+	"golang.org/x/crypto/ssh"
 )
 
 type NodeList []*Node
@@ -39,10 +42,15 @@ func (l NodeList) Filter(fn func(*Node) bool) NodeList {
 }
 
 // Connect each Node in NodeList to their respective servers.
-func (l NodeList) Connect() error {
+// Nodes which are already connected are skipped, so Connect can be retried
+// after a failure.
+func (l NodeList) Connect() (err error) {
+
+	ctx, span := l.span("Connect")
+	defer func() { endSpan(span, err) }()
 
 	for _, n := range l {
-		if err := n.Connect(); err != nil {
+		if err := n.connect(ctx); err != nil && err != ErrConnected {
 			return err
 		}
 	}
@@ -51,10 +59,11 @@ func (l NodeList) Connect() error {
 }
 
 // Close the connections for each Node in the NodeList.
+// Nodes which are not connected are skipped.
 func (l NodeList) Close() error {
 
 	for _, n := range l {
-		if err := n.Close(); err != nil {
+		if err := n.Close(); err != nil && err != ErrNotConnected {
 			return err
 		}
 	}
@@ -64,40 +73,125 @@ func (l NodeList) Close() error {
 
 // Perform an operation against each Node in the NodeList.
 // The result will be channel of Responses for each Node in the NodeList.
+// The operation must either respond, or return an error without responding.
+// For each Node whose operation returns an error, a Response carrying the
+// error is sent instead, and the errors are returned as NodeErrors. The
+// channel always receives a Response for every Node before it is closed.
 func (l NodeList) Each(fn func(*Node, func(Response) error) error) (chan Response, error) {
+	return l.each("Each", func(_ context.Context, n *Node, respond func(Response) error) error {
+		return fn(n, respond)
+	})
+}
+
+// Perform an operation against each Node in the NodeList, as Each, tracing
+// it as op. The operation is given the context holding the Span for its Node.
+func (l NodeList) each(op string, fn func(context.Context, *Node, func(Response) error) error) (chan Response, error) {
+
+	ctx, span := l.span(op)
 
 	var wg sync.WaitGroup
 	wg.Add(len(l))
 
 	responses := make(chan Response, len(l))
 
+	var errs NodeErrors
+
 	for _, n := range l {
 
+		nctx, nspan := n.span(ctx, "node")
+
 		respond := func(res Response) error {
+			endResponse(nspan, res)
 			responses <- res
 			wg.Done()
 			return nil
 		}
 
-		if err := fn(n, respond); err != nil {
-			println(err)
+		if err := fn(nctx, n, respond); err != nil {
+			err = n.Secrets.maskError(err)
+			n.log().Error("request failed", "error", err)
+			errs = append(errs, &NodeError{Node: n, Err: err})
+			respond(errorResponse(n, err))
 		}
 	}
 
 	go func() {
 		wg.Wait()
+		span.End()
 		close(responses)
 	}()
 
+	if len(errs) > 0 {
+		return responses, errs
+	}
+
 	return responses, nil
 }
 
+// Set the Retry policy of each Node in the NodeList.
+// The policy applies to every operation which does not set its own.
+func (l NodeList) SetRetry(policy *Retry) {
+	for _, n := range l {
+		n.Retry = policy
+	}
+}
+
+// Set the host key verification of each Node in the NodeList.
+func (l NodeList) SetHostKey(hostKey ssh.HostKeyCallback) {
+	for _, n := range l {
+		n.HostKey = hostKey
+	}
+}
+
+// Set the Pool each Node in the NodeList shares SSH connections from.
+// This takes effect when the Nodes are next connected.
+func (l NodeList) SetPool(pool *Pool) {
+	for _, n := range l {
+		n.Pool = pool
+	}
+}
+
+// Send a signal to the command running on each Node in the NodeList.
+func (l NodeList) Signal(sig ssh.Signal) error {
+
+	var first error
+
+	for _, n := range l {
+		if err := n.Signal(sig); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Cancel the command running on each Node in the NodeList.
+func (l NodeList) Cancel() error {
+
+	var first error
+
+	for _, n := range l {
+		if err := n.Cancel(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
 // Execute a Request against each Node in the NodeList.
 // The result will be channel of Responses for each Node in the NodeList.
 func (l NodeList) Execute(req Request) (chan Response, error) {
-	return l.Each(func(n *Node, respond func(Response) error) error {
+	return l.execute("Execute", req)
+}
+
+// Execute a Request against each Node in the NodeList, tracing it as op.
+func (l NodeList) execute(op string, req Request) (chan Response, error) {
+	return l.each(op, func(ctx context.Context, n *Node, respond func(Response) error) error {
 		req.Respond = respond
-		return n.Execute(req)
+		req.ctx = ctx
+		_, err := n.Execute(req)
+		return err
 	})
 }
 
@@ -108,7 +202,7 @@ func (l NodeList) Run(command string) (chan Response, error) {
 		Command: command,
 	}
 
-	return l.Execute(req)
+	return l.execute("Run", req)
 }
 
 // Copy a file from src to dest on each Node.
@@ -129,22 +223,27 @@ func (l NodeList) Copy(src string, dest string) (chan Response, error) {
 		return nil, err
 	}
 
-	return l.Each(func(n *Node, respond func(Response) error) error {
+	return l.each("Copy", func(ctx context.Context, n *Node, respond func(Response) error) error {
 
 		req := Request{
-			Command: command,
-			Stdin:   input.Bytes(),
-			Respond: respond,
+			Command:  command,
+			Stdin:    input.Bytes(),
+			Respond:  respond,
+			ctx:      ctx,
+			transfer: dest,
 		}
 
-		return n.Execute(req)
+		n.logTransfer(dest, input.Len())
+
+		_, err := n.Execute(req)
+		return err
 	})
 }
 
 // Write a file from at dest on each Node.
 // The result will be channel of Responses for each Node in the NodeList.
 func (l NodeList) Write(dest string, content *bytes.Reader) (chan Response, error) {
-	return l.Each(func(n *Node, respond func(Response) error) error {
+	return l.each("Write", func(ctx context.Context, n *Node, respond func(Response) error) error {
 
 		stdin := new(bytes.Buffer)
 		if _, err := io.Copy(stdin, content); err != nil {
@@ -152,25 +251,35 @@ func (l NodeList) Write(dest string, content *bytes.Reader) (chan Response, erro
 		}
 
 		req := Request{
-			Command: "cat - > " + dest,
-			Stdin:   stdin.Bytes(),
-			Respond: respond,
+			Command:  "cat - > " + dest,
+			Stdin:    stdin.Bytes(),
+			Respond:  respond,
+			ctx:      ctx,
+			transfer: dest,
 		}
 
-		return n.Execute(req)
+		n.logTransfer(dest, stdin.Len())
+
+		_, err := n.Execute(req)
+		return err
 	})
 }
 
 // Write a file from at dest on each Node.
 // The result will be channel of Responses for each Node in the NodeList.
 func (l NodeList) WriteBytes(dest string, content []byte) (chan Response, error) {
-	return l.Each(func(n *Node, respond func(Response) error) error {
+	return l.each("WriteBytes", func(ctx context.Context, n *Node, respond func(Response) error) error {
 		req := Request{
-			Command: "cat - > " + dest,
-			Stdin:   content,
-			Respond: respond,
+			Command:  "cat - > " + dest,
+			Stdin:    content,
+			Respond:  respond,
+			ctx:      ctx,
+			transfer: dest,
 		}
 
-		return n.Execute(req)
+		n.logTransfer(dest, len(content))
+
+		_, err := n.Execute(req)
+		return err
 	})
 }
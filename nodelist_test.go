@@ -0,0 +1,78 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestEachErrors(t *testing.T) {
+
+	refused := errors.New("refused")
+	nodes := disconnected("node1", "node2", "node3")
+
+	responses, err := nodes.Each(func(n *gommander.Node, respond func(gommander.Response) error) error {
+		if n.Host == "node2" {
+			return refused
+		}
+		return respond(gommander.Response{Node: n, Stdout: new(bytes.Buffer), Stderr: new(bytes.Buffer)})
+	})
+
+	var nodeErrors gommander.NodeErrors
+	if !errors.As(err, &nodeErrors) || len(nodeErrors) != 1 || nodeErrors[0].Node != nodes[1] {
+		t.Fatalf("expected NodeErrors for node2, got %v", err)
+	}
+	if !errors.Is(err, refused) {
+		t.Errorf("expected the NodeErrors to wrap the error, got %v", err)
+	}
+	if err.Error() != "node2: refused" {
+		t.Errorf("unexpected message %q", err)
+	}
+
+	// Every Node gets a Response, carrying the error if it failed
+	count := 0
+	for r := range responses {
+		count++
+		if failed := r.Err == refused; failed != (r.Node.Host == "node2") {
+			t.Errorf("%s: unexpected error %v", r.Node.Host, r.Err)
+		}
+		if r.Stdout == nil || r.Stderr == nil {
+			t.Errorf("%s: expected empty output buffers", r.Node.Host)
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected 3 Responses, got %d", count)
+	}
+}
+
+func TestRunNotConnected(t *testing.T) {
+
+	responses, err := disconnected("node1", "node2").Run("true")
+
+	var nodeErrors gommander.NodeErrors
+	if !errors.As(err, &nodeErrors) || len(nodeErrors) != 2 {
+		t.Fatalf("expected NodeErrors for both Nodes, got %v", err)
+	}
+
+	for r := range responses {
+		if r.Err == nil {
+			t.Errorf("%s: expected an error", r.Node.Host)
+		}
+	}
+}
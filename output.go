@@ -0,0 +1,154 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Open the stdout of the command, whether captured in memory or spooled
+// to a file.
+func (r Response) StdoutReader() (io.ReadCloser, error) {
+	return openOutput(r.StdoutFile, r.Stdout)
+}
+
+// Open the stderr of the command, whether captured in memory or spooled
+// to a file.
+func (r Response) StderrReader() (io.ReadCloser, error) {
+	return openOutput(r.StderrFile, r.Stderr)
+}
+
+// Remove the files output was spooled to, if any.
+func (r Response) Remove() error {
+	var first error
+	for _, f := range []string{r.StdoutFile, r.StderrFile} {
+		if len(f) == 0 {
+			continue
+		}
+		if err := os.Remove(f); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func openOutput(file string, buf *bytes.Buffer) (io.ReadCloser, error) {
+	if len(file) > 0 {
+		return os.Open(file)
+	}
+	if buf == nil {
+		return ioutil.NopCloser(new(bytes.Reader)), nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(buf.Bytes())), nil
+}
+
+// The destinations of the output of a command. Output is captured in the
+// Response buffers, or spooled to files, up to the limit of the Request.
+type output struct {
+	stdout *limitWriter
+	stderr *limitWriter
+	files  []*os.File
+}
+
+// Create the output destinations for a Request.
+func newOutput(n *Node, req *Request, res *Response) (*output, error) {
+
+	var stdout, stderr io.Writer = res.Stdout, res.Stderr
+	o := &output{}
+
+	if req.Spool {
+		pattern := "gommander-" + strings.Replace(n.Host, string(os.PathSeparator), "_", -1) + "-*"
+
+		out, err := os.CreateTemp(req.SpoolDir, pattern+".stdout")
+		if err != nil {
+			return nil, err
+		}
+		o.files = append(o.files, out)
+
+		errf, err := os.CreateTemp(req.SpoolDir, pattern+".stderr")
+		if err != nil {
+			o.close(res)
+			return nil, err
+		}
+		o.files = append(o.files, errf)
+
+		res.StdoutFile = out.Name()
+		res.StderrFile = errf.Name()
+		stdout, stderr = out, errf
+	}
+
+	o.stdout = &limitWriter{w: stdout, limit: req.MaxOutput}
+	o.stderr = &limitWriter{w: stderr, limit: req.MaxOutput}
+
+	return o, nil
+}
+
+// Complete the output, marking any truncation and closing spool files.
+func (o *output) close(res *Response) error {
+
+	for _, w := range []*limitWriter{o.stdout, o.stderr} {
+		if w != nil && w.dropped > 0 {
+			fmt.Fprintf(w.w, "\n[truncated %d bytes]\n", w.dropped)
+			res.Truncated = true
+		}
+	}
+
+	var first error
+	for _, f := range o.files {
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// A Writer which discards everything after a limit of bytes.
+// A limit of zero or less means no limit.
+type limitWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+	dropped int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+
+	n := len(p)
+
+	if l.limit > 0 {
+		room := l.limit - l.written
+		if room < 0 {
+			room = 0
+		}
+		if int64(len(p)) > room {
+			l.dropped += int64(len(p)) - room
+			p = p[:room]
+		}
+	}
+
+	if len(p) > 0 {
+		if _, err := l.w.Write(p); err != nil {
+			return 0, err
+		}
+		l.written += int64(len(p))
+	}
+
+	return n, nil
+}
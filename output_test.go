@@ -0,0 +1,93 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestMaxOutput(t *testing.T) {
+
+	n := &gommander.Node{Host: "node1"}
+	res := &gommander.Response{Stdout: new(bytes.Buffer), Stderr: new(bytes.Buffer)}
+
+	err := gommander.WriteOutput(n, &gommander.Request{MaxOutput: 10}, res, strings.Repeat("x", 100), "err")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.Truncated {
+		t.Error("expected the Response to be truncated")
+	}
+	if expected := strings.Repeat("x", 10) + "\n[truncated 90 bytes]\n"; res.Stdout.String() != expected {
+		t.Errorf("expected %q, got %q", expected, res.Stdout)
+	}
+	if res.Stderr.String() != "err" {
+		t.Errorf("expected stderr within the limit to be kept, got %q", res.Stderr)
+	}
+}
+
+func TestSpool(t *testing.T) {
+
+	n := &gommander.Node{Host: "10.0.0.1/app"}
+	res := &gommander.Response{Stdout: new(bytes.Buffer), Stderr: new(bytes.Buffer)}
+	dir := t.TempDir()
+
+	err := gommander.WriteOutput(n, &gommander.Request{Spool: true, SpoolDir: dir}, res, strings.Repeat("x", 100), "err")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.StdoutFile) == 0 || len(res.StderrFile) == 0 {
+		t.Fatal("expected output to be spooled")
+	}
+	if !strings.HasPrefix(res.StdoutFile, dir) || strings.Contains(res.StdoutFile[len(dir):], "/app") {
+		t.Errorf("expected a file named after the host in %s, got %s", dir, res.StdoutFile)
+	}
+	if res.Stdout.Len() != 0 {
+		t.Errorf("expected no output in memory, got %d bytes", res.Stdout.Len())
+	}
+
+	for expected, open := range map[string]func() (io.ReadCloser, error){
+		strings.Repeat("x", 100): res.StdoutReader,
+		"err":                    res.StderrReader,
+	} {
+		rc, err := open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+
+		if string(content) != expected {
+			t.Errorf("expected spooled %q, got %q", expected, content)
+		}
+	}
+
+	if err := res.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{res.StdoutFile, res.StderrFile} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", f)
+		}
+	}
+}
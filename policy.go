@@ -0,0 +1,159 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+)
+
+// ErrSkipped is the Response error for a Node which was not sent the
+// Request, because the operation had already been aborted.
+var ErrSkipped = errors.New("Skipped.")
+
+// ErrFailFast is returned by an operation aborted by a failure.
+var ErrFailFast = errors.New("Aborted on failure.")
+
+// ErrNoQuorum is returned by an operation which did not reach its quorum.
+var ErrNoQuorum = errors.New("Quorum not reached.")
+
+// Policy determines whether an operation against a NodeList succeeds,
+// and when it is aborted.
+// With FailFast, the first failure aborts the operation. With a quorum,
+// the operation succeeds if at least the quorum of Nodes succeed, and is
+// aborted once that is no longer possible.
+// Aborting cancels the Jobs in flight, and skips the Nodes not yet sent
+// the Request.
+type Policy struct {
+
+	// Abort on the first failure
+	FailFast bool
+
+	// Minimum number of Nodes which must succeed
+	Quorum int
+
+	// Minimum fraction of Nodes which must succeed, from 0 to 1
+	QuorumFraction float64
+}
+
+// Number of Nodes out of total which must succeed, or 0 for no quorum.
+func (p Policy) required(total int) int {
+
+	required := p.Quorum
+
+	if p.QuorumFraction > 0 {
+		if f := int(math.Ceil(p.QuorumFraction * float64(total))); f > required {
+			required = f
+		}
+	}
+
+	if required > total {
+		required = total
+	}
+
+	return required
+}
+
+// Execute a Request against each Node in the NodeList, according to
+// the Policy. The result is the collected Responses, and ErrFailFast or
+// ErrNoQuorum if the operation did not succeed, or otherwise the NodeErrors
+// of any Nodes the Request could not be sent to.
+func (l NodeList) ExecutePolicy(req Request, p Policy) (*Results, error) {
+
+	required := p.required(len(l))
+
+	var (
+		mu       sync.Mutex
+		jobs     []*Job
+		failures int
+		aborted  bool
+	)
+
+	// Count a failure, aborting the operation if it can no longer succeed
+	account := func(res Response) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if succeeded(res) || res.Err == ErrSkipped {
+			return
+		}
+
+		failures++
+		if aborted || !(p.FailFast || (required > 0 && failures > len(l)-required)) {
+			return
+		}
+
+		aborted = true
+		for _, j := range jobs {
+			j.Cancel()
+		}
+	}
+
+	responses, err := l.each("ExecutePolicy", func(ctx context.Context, n *Node, respond func(Response) error) error {
+
+		mu.Lock()
+		skip := aborted
+		mu.Unlock()
+
+		if skip {
+			return respond(errorResponse(n, ErrSkipped))
+		}
+
+		r := req
+		r.ctx = ctx
+		r.Respond = func(res Response) error {
+			account(res)
+			return respond(res)
+		}
+
+		job, err := n.Execute(r)
+		if err != nil {
+			account(errorResponse(n, err))
+			return err
+		}
+
+		mu.Lock()
+		jobs = append(jobs, job)
+		if aborted {
+			job.Cancel()
+		}
+		mu.Unlock()
+
+		return nil
+	})
+
+	results, err := Collect(responses, err)
+	if results == nil {
+		return nil, err
+	}
+
+	count := len(results.Succeeded())
+
+	switch {
+	case p.FailFast && count < len(l):
+		return results, ErrFailFast
+	case count < required:
+		return results, ErrNoQuorum
+	}
+
+	return results, err
+}
+
+// Run a command against each Node in the NodeList, according to the Policy.
+func (l NodeList) RunPolicy(command string, p Policy) (*Results, error) {
+	return l.ExecutePolicy(Request{Command: command}, p)
+}
@@ -0,0 +1,157 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestPolicyRequired(t *testing.T) {
+
+	cases := []struct {
+		policy   gommander.Policy
+		required int
+	}{
+		{gommander.Policy{}, 0},
+		{gommander.Policy{FailFast: true}, 0},
+		{gommander.Policy{Quorum: 3}, 3},
+		{gommander.Policy{Quorum: 5}, 4},
+		{gommander.Policy{QuorumFraction: 0.5}, 2},
+		{gommander.Policy{QuorumFraction: 0.6}, 3},
+		{gommander.Policy{Quorum: 1, QuorumFraction: 0.5}, 2},
+		{gommander.Policy{Quorum: 4, QuorumFraction: 0.5}, 4},
+	}
+
+	for _, c := range cases {
+		if required := c.policy.Required(4); required != c.required {
+			t.Errorf("%+v: expected %d of 4, got %d", c.policy, c.required, required)
+		}
+	}
+}
+
+// Results for failing fake Nodes, followed by succeeding ones which take delay.
+func outcomes(failing, succeeding int, delay time.Duration) []gommander.FakeResult {
+	var results []gommander.FakeResult
+	for i := 0; i < failing; i++ {
+		results = append(results, gommander.FakeResult{ExitCode: 1})
+	}
+	for i := 0; i < succeeding; i++ {
+		results = append(results, gommander.FakeResult{Delay: delay})
+	}
+	return results
+}
+
+func TestFailFast(t *testing.T) {
+
+	nodes := fakes(t, outcomes(1, 3, time.Minute)...)
+
+	start := time.Now()
+	results, err := nodes.RunPolicy("cmd", gommander.Policy{FailFast: true})
+	if err != gommander.ErrFailFast {
+		t.Fatalf("expected ErrFailFast, got %v", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("operation was not aborted, took %s", d)
+	}
+
+	if s := results.Summary(); s.Total != 4 || s.Succeeded != 0 {
+		t.Errorf("expected a Response for each Node, and none to succeed, got %+v", s)
+	}
+
+	// Depending on how far each got, the rest are killed, cancelled or skipped
+	for _, r := range results.Responses {
+		switch {
+		case r.Node.Host == "node1", r.Signal == "KILL", r.Err == gommander.ErrCancelled, r.Err == gommander.ErrSkipped:
+		default:
+			t.Errorf("expected %s to be aborted, got signal %q, error %v", r.Node.Host, r.Signal, r.Err)
+		}
+	}
+}
+
+func TestFailFastSkipped(t *testing.T) {
+
+	results, err := disconnected("node1", "node2", "node3").RunPolicy("true", gommander.Policy{FailFast: true})
+	if err != gommander.ErrFailFast {
+		t.Fatalf("expected ErrFailFast, got %v", err)
+	}
+	if len(results.Responses) != 3 {
+		t.Fatalf("expected a Response for each Node, got %d", len(results.Responses))
+	}
+
+	// The first failure aborts the operation, skipping the rest
+	for i, r := range results.Responses {
+		if skipped := r.Err == gommander.ErrSkipped; skipped != (i > 0) {
+			t.Errorf("%s: unexpected error %v", r.Node.Host, r.Err)
+		}
+	}
+}
+
+func TestNoQuorum(t *testing.T) {
+
+	results, err := disconnected("node1", "node2", "node3").RunPolicy("true", gommander.Policy{Quorum: 1})
+	if err != gommander.ErrNoQuorum {
+		t.Fatalf("expected ErrNoQuorum, got %v", err)
+	}
+
+	// The quorum is reachable until the last Node fails
+	if s := results.Summary(); s.Total != 3 || s.Errored != 3 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	for _, r := range results.Responses {
+		if r.Err == gommander.ErrSkipped {
+			t.Errorf("%s: expected the Request to be sent", r.Node.Host)
+		}
+	}
+}
+
+func TestQuorum(t *testing.T) {
+
+	cases := []struct {
+		name      string
+		failing   int
+		policy    gommander.Policy
+		err       error
+		succeeded int
+	}{
+		{"reached", 1, gommander.Policy{Quorum: 3}, nil, 3},
+		{"not reached", 2, gommander.Policy{Quorum: 3}, gommander.ErrNoQuorum, 0},
+		{"fraction reached", 2, gommander.Policy{QuorumFraction: 0.5}, nil, 2},
+		{"fraction not reached", 2, gommander.Policy{QuorumFraction: 0.75}, gommander.ErrNoQuorum, 0},
+		{"no quorum", 4, gommander.Policy{}, nil, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+
+			nodes := fakes(t, outcomes(c.failing, 4-c.failing, 200*time.Millisecond)...)
+
+			results, err := nodes.RunPolicy("cmd", c.policy)
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if len(results.Responses) != 4 {
+				t.Fatalf("expected 4 Responses, got %d", len(results.Responses))
+			}
+
+			// Once the quorum cannot be reached, the rest are cancelled
+			if n := len(results.Succeeded()); n != c.succeeded {
+				t.Errorf("expected %d to succeed, got %d", c.succeeded, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrPoolFull is returned when connecting a Node would exceed the
+// connections allowed by its Pool.
+var ErrPoolFull = errors.New("Connection pool is full.")
+
+// Pool shares SSH connections between Nodes, like OpenSSH ControlMaster.
+// Nodes share a connection when they have the same Host, Port and User,
+// and the same Auth slice, as when each is given the same slice by NewNode.
+// Auth methods cannot be compared, so Nodes given copies of a slice do not
+// share, and changing an element of a shared slice is not noticed.
+// A Node sharing a connection verifies the host key it was opened with,
+// by its own HostKey. Connections are reference counted, and closed once
+// unused for the IdleTimeout.
+type Pool struct {
+
+	// Time an unused connection is kept open. Zero closes it immediately.
+	IdleTimeout time.Duration
+
+	// Maximum number of connections. Zero means no maximum.
+	// Unused connections are closed early to stay within it.
+	MaxConns int
+
+	mu    sync.Mutex
+	conns map[poolKey]*poolConn
+}
+
+// Identity of a connection. Auth methods are not comparable, so the
+// Auth slice is identified by the address of its first element, and its
+// length.
+type poolKey struct {
+	host  string
+	port  uint
+	user  string
+	auth  *ssh.AuthMethod
+	nauth int
+}
+
+type poolConn struct {
+	key     poolKey
+	client  *ssh.Client
+	hostKey ssh.PublicKey
+	err     error
+	ready   chan struct{}
+	refs    int
+	idle    *time.Timer
+}
+
+// Create a Pool.
+func NewPool(idleTimeout time.Duration, maxConns int) *Pool {
+	return &Pool{
+		IdleTimeout: idleTimeout,
+		MaxConns:    maxConns,
+		conns:       map[poolKey]*poolConn{},
+	}
+}
+
+// Number of open connections.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// Close every connection, whether in use or not.
+func (p *Pool) Close() error {
+
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = map[poolKey]*poolConn{}
+	p.mu.Unlock()
+
+	var first error
+	for _, c := range conns {
+		<-c.ready
+		if c.idle != nil {
+			c.idle.Stop()
+		}
+		if c.client != nil {
+			if err := c.client.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func keyOf(n *Node) poolKey {
+	k := poolKey{host: n.Host, port: n.Port, user: n.User, nauth: len(n.Auth)}
+	if len(n.Auth) > 0 {
+		k.auth = &n.Auth[0]
+	}
+	return k
+}
+
+// Get a connection for the key, dialling one if none is shared. A shared
+// connection is only returned if verify accepts the host key it was
+// opened with.
+func (p *Pool) acquire(key poolKey, verify func(*ssh.Client, ssh.PublicKey) error, dial func() (*ssh.Client, ssh.PublicKey, error)) (*ssh.Client, error) {
+
+	p.mu.Lock()
+
+	if p.conns == nil {
+		p.conns = map[poolKey]*poolConn{}
+	}
+
+	if c, ok := p.conns[key]; ok {
+		c.refs++
+		if c.idle != nil {
+			c.idle.Stop()
+			c.idle = nil
+		}
+		p.mu.Unlock()
+
+		<-c.ready
+		if c.err != nil {
+			return nil, c.err
+		}
+		if err := verify(c.client, c.hostKey); err != nil {
+			p.release(key, c.client)
+			return nil, err
+		}
+		return c.client, nil
+	}
+
+	if p.MaxConns > 0 && len(p.conns) >= p.MaxConns && !p.evict() {
+		p.mu.Unlock()
+		return nil, ErrPoolFull
+	}
+
+	c := &poolConn{key: key, ready: make(chan struct{}), refs: 1}
+	p.conns[key] = c
+	p.mu.Unlock()
+
+	c.client, c.hostKey, c.err = dial()
+
+	if c.err != nil {
+		p.mu.Lock()
+		if p.conns[key] == c {
+			delete(p.conns, key)
+		}
+		p.mu.Unlock()
+		close(c.ready)
+		return nil, c.err
+	}
+
+	close(c.ready)
+
+	// Forget the connection if it fails
+	go func() {
+		c.client.Wait()
+		p.mu.Lock()
+		if p.conns[key] == c {
+			delete(p.conns, key)
+		}
+		p.mu.Unlock()
+	}()
+
+	return c.client, nil
+}
+
+// Return a connection acquired for the key.
+func (p *Pool) release(key poolKey, client *ssh.Client) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.conns[key]
+	if !ok || c.client != client {
+		// No longer pooled
+		client.Close()
+		return
+	}
+
+	c.refs--
+	if c.refs > 0 {
+		return
+	}
+
+	if p.IdleTimeout <= 0 {
+		delete(p.conns, key)
+		client.Close()
+		return
+	}
+
+	c.idle = time.AfterFunc(p.IdleTimeout, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.conns[key] == c && c.refs == 0 {
+			delete(p.conns, key)
+			client.Close()
+		}
+	})
+}
+
+// Close an unused connection to make room for another.
+// Called with mu held.
+func (p *Pool) evict() bool {
+	for key, c := range p.conns {
+		if c.refs == 0 && c.client != nil {
+			if c.idle != nil {
+				c.idle.Stop()
+			}
+			delete(p.conns, key)
+			c.client.Close()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+	"golang.org/x/crypto/ssh"
+)
+
+func client(n *gommander.Node) *ssh.Client {
+	return n.Transport.(*gommander.SSHTransport).Client()
+}
+
+func TestPoolShare(t *testing.T) {
+
+	server := serve(t, gommandertest.Config{
+		Handler: gommandertest.Script(map[string]gommandertest.Result{"true": {}}),
+	})
+
+	pool := gommander.NewPool(100*time.Millisecond, 0)
+	defer pool.Close()
+
+	nodes := gommander.NodeList{server.Node("test", nil), server.Node("test", nil)}
+	nodes.SetPool(pool)
+
+	if err := nodes.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if pool.Len() != 1 || client(nodes[0]) != client(nodes[1]) {
+		t.Fatalf("expected the Nodes to share 1 connection, pool has %d", pool.Len())
+	}
+	shared := client(nodes[0])
+
+	results, err := gommander.Collect(nodes.Run("true"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := results.Summary(); s.Succeeded != 2 {
+		t.Errorf("expected both commands to succeed, got %+v", s)
+	}
+
+	// The connection is kept while referenced, then until idle
+	nodes[0].Close()
+	if pool.Len() != 1 {
+		t.Fatal("connection closed while still in use")
+	}
+
+	results, err = gommander.Collect(gommander.NodeList{nodes[1]}.Run("true"))
+	if err != nil || results.Responses[0].Err != nil {
+		t.Fatalf("connection unusable after the other Node closed: %v", err)
+	}
+
+	nodes[1].Close()
+	if pool.Len() != 1 {
+		t.Fatal("connection closed before the idle timeout")
+	}
+
+	// A Node connecting while idle reuses the connection
+	n := server.Node("test", nil)
+	n.Pool = pool
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if client(n) != shared {
+		t.Fatal("expected the idle connection to be reused")
+	}
+	n.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle connection never closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolNoIdle(t *testing.T) {
+
+	server := serve(t, gommandertest.Config{})
+
+	pool := gommander.NewPool(0, 0)
+	defer pool.Close()
+
+	n := server.Node("test", nil)
+	n.Pool = pool
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Len() != 1 {
+		t.Fatalf("expected 1 connection, pool has %d", pool.Len())
+	}
+
+	n.Close()
+	if pool.Len() != 0 {
+		t.Fatal("expected the connection to close once unused")
+	}
+}
+
+func TestPoolMaxConns(t *testing.T) {
+
+	cluster, err := gommandertest.NewCluster(2, gommandertest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	pool := gommander.NewPool(time.Minute, 1)
+	defer pool.Close()
+
+	first := cluster[0].Node("test", nil)
+	first.Pool = pool
+	if err := first.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	second := cluster[1].Node("test", nil)
+	second.Pool = pool
+	if err := second.Connect(); err != gommander.ErrPoolFull {
+		t.Fatalf("expected ErrPoolFull while the connection is in use, got %v", err)
+	}
+
+	// An idle connection is evicted to make room
+	first.Close()
+
+	second = cluster[1].Node("test", nil)
+	second.Pool = pool
+	if err := second.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if pool.Len() != 1 {
+		t.Errorf("expected 1 connection, pool has %d", pool.Len())
+	}
+}
+
+func TestPoolHostKey(t *testing.T) {
+
+	server := serve(t, gommandertest.Config{})
+	other := serve(t, gommandertest.Config{})
+
+	pool := gommander.NewPool(0, 0)
+	defer pool.Close()
+
+	insecure := server.Node("test", nil)
+	insecure.HostKey = ssh.InsecureIgnoreHostKey()
+	insecure.Pool = pool
+	connect(t, insecure)
+
+	// A strict Node verifies the host key of the shared connection
+	strict := server.Node("test", nil)
+	strict.HostKey = ssh.FixedHostKey(other.HostKey())
+	strict.Pool = pool
+	if err := strict.Connect(); err == nil {
+		strict.Close()
+		t.Fatal("expected the host key to be rejected")
+	}
+
+	trusting := server.Node("test", nil)
+	trusting.Pool = pool
+	connect(t, trusting)
+
+	if client(trusting) != client(insecure) {
+		t.Error("expected a Node accepting the host key to share the connection")
+	}
+
+	// The rejected Node does not hold the connection open
+	insecure.Close()
+	trusting.Close()
+	if pool.Len() != 0 {
+		t.Errorf("expected the connection to be closed, pool has %d", pool.Len())
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"golang.org/x/crypto/ssh"
+)
+
+// Pty describes a pseudo-terminal to allocate for a Request.
+// Some programs refuse to run, or behave differently, without a terminal.
+// The terminal merges stderr into stdout, so the output of a command run
+// with a Pty is found entirely in Response.Stdout.
+type Pty struct {
+
+	// Terminal type. Defaults to "xterm".
+	Term string
+
+	// Window width, in characters. Defaults to 80.
+	Width int
+
+	// Window height, in characters. Defaults to 24.
+	Height int
+
+	// Terminal modes. Defaults to no echo, so that Stdin is not
+	// copied into the output.
+	Modes ssh.TerminalModes
+
+	// Window change events. Each size received is sent to the server
+	// while the command is running.
+	Resize chan WindowSize
+}
+
+// WindowSize is the size of a terminal window, in characters.
+type WindowSize struct {
+	Width  int
+	Height int
+}
+
+// Request a pseudo-terminal for the session.
+func requestPty(session Session, pty *Pty) error {
+
+	term := pty.Term
+	if len(term) == 0 {
+		term = "xterm"
+	}
+
+	width := pty.Width
+	if width <= 0 {
+		width = 80
+	}
+
+	height := pty.Height
+	if height <= 0 {
+		height = 24
+	}
+
+	modes := pty.Modes
+	if modes == nil {
+		modes = ssh.TerminalModes{
+			ssh.ECHO:          0,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+	}
+
+	return session.RequestPty(term, height, width, modes)
+}
+
+// Forward window change events to the session, until done is closed.
+func forwardResize(session Session, resize chan WindowSize, done chan struct{}) {
+	for {
+		select {
+		case size, ok := <-resize:
+			if !ok {
+				return
+			}
+			session.WindowChange(size.Height, size.Width)
+		case <-done:
+			return
+		}
+	}
+}
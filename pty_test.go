@@ -0,0 +1,40 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+func TestPty(t *testing.T) {
+
+	n := connect(t, serve(t, gommandertest.Config{}).Node("test", nil))[0]
+
+	results, err := gommander.Collect(gommander.NodeList{n}.Execute(gommander.Request{
+		Command: "echo out; echo err >&2",
+		Pty:     &gommander.Pty{},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := results.Responses[0]
+	if !r.Pty || r.Stdout.String() != "out\nerr\n" || r.Stderr.Len() != 0 {
+		t.Errorf("expected stderr merged into stdout, got %q, %q", r.Stdout, r.Stderr)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Results are the Responses of an operation against a NodeList, with
+// Nodes grouped by identical output.
+type Results struct {
+
+	// Responses, in the order they were received
+	Responses []Response
+
+	// Groups of Nodes with identical output, in the order
+	// their first Response was received
+	Groups []*Group
+}
+
+// Group is a set of Nodes whose Responses had identical exit code,
+// error, stdout and stderr.
+type Group struct {
+
+	// Nodes in the group
+	Nodes NodeList
+
+	// Response of the first Node in the group, representing them all
+	Response Response
+
+	key string
+}
+
+// Summary counts the outcomes of an operation.
+type Summary struct {
+
+	// Number of Responses
+	Total int
+
+	// Number of commands which exited with 0
+	Succeeded int
+
+	// Number of commands which exited with a non-zero code
+	Failed int
+
+	// Number of Requests which failed with an error
+	Errored int
+}
+
+// Collect the Responses from an operation into Results.
+// The arguments match the results of NodeList operations, so they can be
+// passed directly, e.g. Collect(nodes.Run("hostname")). The error of the
+// operation, such as NodeErrors, is returned along with the Results, which
+// include a Response for each Node the operation failed on. If spooled
+// output cannot be read, the error is joined to the Err of its Response.
+func Collect(responses chan Response, err error) (*Results, error) {
+
+	if responses == nil {
+		return nil, err
+	}
+
+	results := &Results{}
+	groups := map[string]*Group{}
+
+	for r := range responses {
+
+		key, gerr := groupKey(r)
+		if gerr != nil {
+			r.Err = errors.Join(r.Err, gerr)
+			key = fmt.Sprintf("unread\x00%d\x00%v", r.ExitCode, r.Err)
+		}
+
+		results.Responses = append(results.Responses, r)
+
+		g, ok := groups[key]
+		if !ok {
+			g = &Group{Response: r, key: key}
+			groups[key] = g
+			results.Groups = append(results.Groups, g)
+		}
+		g.Nodes = append(g.Nodes, r.Node)
+	}
+
+	return results, err
+}
+
+// Count the outcomes of the Responses.
+func (r *Results) Summary() Summary {
+
+	return Summary{
+		Total:     len(r.Responses),
+		Succeeded: len(r.Succeeded()),
+		Failed:    len(r.Failed()),
+		Errored:   len(r.Errored()),
+	}
+}
+
+// Nodes whose Response satisfies the predicate function, in the order
+// their Responses were received. This allows an operation to be followed
+// by another against a subset of the Nodes.
+func (r *Results) Select(fn func(Response) bool) NodeList {
+	var l NodeList
+	for _, res := range r.Responses {
+		if fn(res) {
+			l = append(l, res.Node)
+		}
+	}
+	return l
+}
+
+// Nodes whose command exited with 0.
+func (r *Results) Succeeded() NodeList {
+	return r.Select(succeeded)
+}
+
+// Nodes whose command exited with a non-zero code.
+func (r *Results) Failed() NodeList {
+	return r.Select(func(res Response) bool {
+		return res.Err == nil && res.ExitCode != 0
+	})
+}
+
+// Nodes whose Request failed with an error.
+func (r *Results) Errored() NodeList {
+	return r.Select(func(res Response) bool {
+		return res.Err != nil
+	})
+}
+
+// Whether the command of a Response exited with 0.
+func succeeded(res Response) bool {
+	return res.Err == nil && res.ExitCode == 0
+}
+
+// Host names of the Nodes in the group, compacted into ranges.
+func (g *Group) Hosts() string {
+	hosts := make([]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		hosts[i] = n.Name()
+	}
+	return FoldHosts(hosts)
+}
+
+// Name of the Node for display. This is the host, with the port
+// appended if it is not the default SSH port.
+func (n *Node) Name() string {
+	if n.Port == 22 || n.Port == 0 {
+		return n.Host
+	}
+	return net.JoinHostPort(n.Host, strconv.Itoa(int(n.Port)))
+}
+
+// Key identifying Responses with identical output.
+// Output is hashed, so spooled output is never held in memory.
+func groupKey(r Response) (string, error) {
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%v\x00", r.ExitCode, r.Err)
+
+	for _, open := range []func() (io.ReadCloser, error){r.StdoutReader, r.StderrReader} {
+		rc, err := open()
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte{0})
+	}
+
+	return string(h.Sum(nil)), nil
+}
+
+// Splits a host name around its last run of digits.
+var hostNumber = regexp.MustCompile(`^(.*?)(\d+)(\D*)$`)
+
+// FoldHosts compacts host names into ranges on their last number,
+// e.g. "node1", "node2", "node3" and "node5" fold to "node[1-3,5]".
+// Duplicate names are removed.
+func FoldHosts(hosts []string) string {
+
+	type set struct {
+		prefix, suffix string
+		numbers        []string
+	}
+
+	var sets []*set
+	byKey := map[string]*set{}
+	seen := map[string]bool{}
+
+	for _, h := range hosts {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+
+		prefix, number, suffix := h, "", ""
+		if m := hostNumber.FindStringSubmatch(h); m != nil {
+			prefix, number, suffix = m[1], m[2], m[3]
+		}
+
+		key := prefix + "\x00" + suffix
+		if len(number) == 0 {
+			key = h + "\x00\x00"
+		}
+
+		s, ok := byKey[key]
+		if !ok {
+			s = &set{prefix: prefix, suffix: suffix}
+			byKey[key] = s
+			sets = append(sets, s)
+		}
+		if len(number) > 0 {
+			s.numbers = append(s.numbers, number)
+		}
+	}
+
+	folded := make([]string, 0, len(sets))
+	for _, s := range sets {
+		switch len(s.numbers) {
+		case 0:
+			folded = append(folded, s.prefix)
+		case 1:
+			folded = append(folded, s.prefix+s.numbers[0]+s.suffix)
+		default:
+			folded = append(folded, s.prefix+"["+foldNumbers(s.numbers)+"]"+s.suffix)
+		}
+	}
+
+	return strings.Join(folded, ",")
+}
+
+// Fold numbers into ranges, e.g. 1, 2, 3, 5 fold to "1-3,5".
+// Zero padded numbers only join ranges of the same width.
+func foldNumbers(numbers []string) string {
+
+	value := func(s string) uint64 {
+		v, _ := strconv.ParseUint(s, 10, 64)
+		return v
+	}
+
+	sort.Slice(numbers, func(i, j int) bool {
+		vi, vj := value(numbers[i]), value(numbers[j])
+		if vi != vj {
+			return vi < vj
+		}
+		return len(numbers[i]) < len(numbers[j])
+	})
+
+	padded := func(s string) bool {
+		return len(s) > 1 && s[0] == '0'
+	}
+
+	follows := func(prev, next string) bool {
+		if value(next) != value(prev)+1 {
+			return false
+		}
+		if padded(prev) || padded(next) {
+			return len(prev) == len(next)
+		}
+		return true
+	}
+
+	var ranges []string
+	start := 0
+
+	for i := 1; i <= len(numbers); i++ {
+		if i < len(numbers) && follows(numbers[i-1], numbers[i]) {
+			continue
+		}
+		if i-1 == start {
+			ranges = append(ranges, numbers[start])
+		} else {
+			ranges = append(ranges, numbers[start]+"-"+numbers[i-1])
+		}
+		start = i
+	}
+
+	return strings.Join(ranges, ",")
+}
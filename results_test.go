@@ -0,0 +1,212 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestFoldHosts(t *testing.T) {
+
+	cases := []struct {
+		hosts    []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{"node1"}, "node1"},
+		{[]string{"node1", "node2", "node3", "node5"}, "node[1-3,5]"},
+		{[]string{"node3", "node1", "node2", "node1"}, "node[1-3]"},
+		{[]string{"db1", "web1", "db2", "web2"}, "db[1-2],web[1-2]"},
+		{[]string{"rack1-node1.example.com", "rack1-node2.example.com"}, "rack1-node[1-2].example.com"},
+		{[]string{"node08", "node09", "node10"}, "node[08-10]"},
+		{[]string{"localhost", "node1", "localhost"}, "localhost,node1"},
+		{[]string{"10.0.0.1:2222", "10.0.0.1:2223"}, "10.0.0.1:[2222-2223]"},
+	}
+
+	for _, c := range cases {
+		if folded := gommander.FoldHosts(c.hosts); folded != c.expected {
+			t.Errorf("FoldHosts(%q) = %q, expected %q", c.hosts, folded, c.expected)
+		}
+	}
+}
+
+func TestFoldNumbers(t *testing.T) {
+
+	cases := []struct {
+		numbers  []string
+		expected string
+	}{
+		{[]string{"1"}, "1"},
+		{[]string{"5", "1", "2", "3"}, "1-3,5"},
+		{[]string{"1", "3", "5"}, "1,3,5"},
+		{[]string{"9", "10", "11"}, "9-11"},
+		{[]string{"09", "10"}, "09-10"},
+		{[]string{"9", "010", "011"}, "9,010-011"},
+	}
+
+	for _, c := range cases {
+		if folded := gommander.FoldNumbers(c.numbers); folded != c.expected {
+			t.Errorf("foldNumbers(%q) = %q, expected %q", c.numbers, folded, c.expected)
+		}
+	}
+}
+
+// A Response of a Node, as sent by an operation.
+func respond(host string, port uint, exitCode int, stdout string, err error) gommander.Response {
+	return gommander.Response{
+		Node:     &gommander.Node{Host: host, Port: port},
+		ExitCode: exitCode,
+		Stdout:   bytes.NewBufferString(stdout),
+		Stderr:   new(bytes.Buffer),
+		Err:      err,
+	}
+}
+
+// A closed channel of Responses, as returned by NodeList operations.
+func responses(rs ...gommander.Response) chan gommander.Response {
+	ch := make(chan gommander.Response, len(rs))
+	for _, r := range rs {
+		ch <- r
+	}
+	close(ch)
+	return ch
+}
+
+func TestCollect(t *testing.T) {
+
+	refused := errors.New("connection refused")
+
+	results, err := gommander.Collect(responses(
+		respond("node1", 22, 0, "same", nil),
+		respond("node3", 22, 1, "other", nil),
+		respond("node2", 22, 0, "same", nil),
+		respond("node4", 22, 0, "", refused),
+		respond("node5", 2222, 0, "same", nil),
+	), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := results.Summary(); s != (gommander.Summary{Total: 5, Succeeded: 3, Failed: 1, Errored: 1}) {
+		t.Errorf("unexpected summary %+v", s)
+	}
+
+	if len(results.Responses) != 5 || len(results.Groups) != 3 {
+		t.Fatalf("expected 5 responses in 3 groups, got %d in %d", len(results.Responses), len(results.Groups))
+	}
+
+	// Groups are in the order of their first Response
+	for i, expected := range []string{"node[1-2],node5:2222", "node3", "node4"} {
+		if hosts := results.Groups[i].Hosts(); hosts != expected {
+			t.Errorf("group %d: expected %q, got %q", i, expected, hosts)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+
+	results, err := gommander.Collect(responses(
+		respond("node1", 22, 0, "", nil),
+		respond("node2", 22, 1, "", nil),
+		respond("node3", 22, 0, "", errors.New("failed")),
+		respond("node4", 22, 0, "", nil),
+	), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := func(l gommander.NodeList) string {
+		names := make([]string, len(l))
+		for i, n := range l {
+			names[i] = n.Host
+		}
+		return strings.Join(names, ",")
+	}
+
+	cases := map[string]gommander.NodeList{
+		"node1,node4": results.Succeeded(),
+		"node2":       results.Failed(),
+		"node3":       results.Errored(),
+		"node2,node3": results.Select(func(r gommander.Response) bool {
+			return r.Err != nil || r.ExitCode != 0
+		}),
+	}
+
+	for expected, l := range cases {
+		if got := hosts(l); got != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestCollectNodeErrors(t *testing.T) {
+
+	nodes := connect(t, gommander.NewFakeNode("node1", &gommander.FakeTransport{}))
+
+	// A Node which was never connected fails to receive the Request
+	nodes = append(nodes, disconnected("node2")...)
+
+	results, err := gommander.Collect(nodes.Run("cmd"))
+	if results == nil {
+		t.Fatal(err)
+	}
+
+	var nodeErrors gommander.NodeErrors
+	if !errors.As(err, &nodeErrors) || len(nodeErrors) != 1 || nodeErrors[0].Node.Host != "node2" {
+		t.Errorf("expected NodeErrors for node2, got %v", err)
+	}
+
+	if s := results.Summary(); s != (gommander.Summary{Total: 2, Succeeded: 1, Errored: 1}) {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if l := results.Errored(); len(l) != 1 || l[0].Host != "node2" {
+		t.Errorf("unexpected errored nodes %v", l)
+	}
+}
+
+func TestCollectUnreadable(t *testing.T) {
+
+	lost := respond("node2", 22, 0, "", nil)
+	lost.StdoutFile = filepath.Join(t.TempDir(), "missing")
+
+	results, err := gommander.Collect(responses(respond("node1", 22, 0, "", nil), lost), nil)
+	if err != nil || results == nil {
+		t.Fatalf("expected Results, got %v, %v", results, err)
+	}
+
+	// The read error is attached to its Response, which is grouped apart
+	if r := results.Responses[1]; !errors.Is(r.Err, fs.ErrNotExist) {
+		t.Errorf("expected the read error on the Response, got %v", r.Err)
+	}
+	if results.Responses[0].Err != nil || len(results.Groups) != 2 {
+		t.Errorf("expected the readable Response in its own group, got %d groups", len(results.Groups))
+	}
+}
+
+func TestCollectError(t *testing.T) {
+
+	expected := errors.New("failed")
+
+	if results, err := gommander.Collect(nil, expected); results != nil || err != expected {
+		t.Errorf("expected no results and the error, got %v, %v", results, err)
+	}
+}
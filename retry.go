@@ -0,0 +1,119 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bufio"
+	"math/rand"
+	"regexp"
+	"time"
+)
+
+// Retry is a policy for retrying Requests which fail transiently.
+// A Response is retried if it matches any of ExitCodes, Stderr or Errors.
+type Retry struct {
+
+	// Maximum number of attempts, including the first
+	Attempts int
+
+	// Delay before the first retry, doubling for each retry after
+	Backoff time.Duration
+
+	// Maximum delay between attempts. Zero means no maximum.
+	MaxBackoff time.Duration
+
+	// Fraction of each delay to randomly add or subtract, from 0 to 1
+	Jitter float64
+
+	// Exit codes to retry, e.g. 75 (EX_TEMPFAIL)
+	ExitCodes []int
+
+	// Pattern of stderr to retry, e.g. lock contention messages.
+	// Spooled stderr is read from its file. With a Pty, stderr is merged
+	// into stdout, so stdout is matched instead.
+	Stderr *regexp.Regexp
+
+	// Predicate on the Response error to retry. If nil, every error is
+	// retried other than ErrCancelled and ErrBecomeAuth.
+	Errors func(error) bool
+}
+
+// Whether a Response should be retried.
+func (p *Retry) retryable(res Response) bool {
+
+	if res.Err != nil {
+		if p.Errors != nil {
+			return p.Errors(res.Err)
+		}
+		return res.Err != ErrCancelled && res.Err != ErrBecomeAuth
+	}
+
+	if res.ExitCode == 0 {
+		return false
+	}
+
+	for _, code := range p.ExitCodes {
+		if res.ExitCode == code {
+			return true
+		}
+	}
+
+	if p.Stderr != nil && p.matchStderr(res) {
+		return true
+	}
+
+	return false
+}
+
+// Whether the stderr of the Response matches the Stderr pattern.
+// Output which cannot be read does not match.
+func (p *Retry) matchStderr(res Response) bool {
+
+	open := res.StderrReader
+	if res.Pty {
+		open = res.StdoutReader
+	}
+
+	rc, err := open()
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+
+	return p.Stderr.MatchReader(bufio.NewReader(rc))
+}
+
+// Delay before the attempt following the given one.
+func (p *Retry) delay(attempt int) time.Duration {
+
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+
+	if d < 0 {
+		d = 0
+	}
+
+	return d
+}
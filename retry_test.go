@@ -0,0 +1,210 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+)
+
+func TestRetryable(t *testing.T) {
+
+	transient := errors.New("connection reset")
+
+	p := &gommander.Retry{
+		Attempts:  3,
+		ExitCodes: []int{75},
+		Stderr:    regexp.MustCompile(`Could not get lock`),
+	}
+
+	cases := []struct {
+		name      string
+		res       gommander.Response
+		retryable bool
+	}{
+		{"success", gommander.Response{}, false},
+		{"exit code", gommander.Response{ExitCode: 75}, true},
+		{"other exit code", gommander.Response{ExitCode: 1}, false},
+		{"stderr", gommander.Response{ExitCode: 100, Stderr: bytes.NewBufferString("E: Could not get lock")}, true},
+		{"pty", gommander.Response{ExitCode: 100, Stdout: bytes.NewBufferString("E: Could not get lock"), Pty: true}, true},
+		{"unreadable", gommander.Response{ExitCode: 100, StderrFile: filepath.Join(t.TempDir(), "missing")}, false},
+		{"error", gommander.Response{Err: transient}, true},
+		{"cancelled", gommander.Response{Err: gommander.ErrCancelled}, false},
+		{"authentication", gommander.Response{Err: gommander.ErrBecomeAuth}, false},
+	}
+
+	for _, c := range cases {
+		if p.Retryable(c.res) != c.retryable {
+			t.Errorf("%s: expected retryable %v", c.name, c.retryable)
+		}
+	}
+
+	p.Errors = func(err error) bool { return err != transient }
+	if p.Retryable(gommander.Response{Err: transient}) {
+		t.Error("expected the Errors predicate to be applied")
+	}
+}
+
+func TestRetry(t *testing.T) {
+
+	transient := errors.New("connection reset")
+	locked := gommander.FakeResult{ExitCode: 100, Stderr: "E: Could not get lock"}
+
+	cases := []struct {
+		name     string
+		failures []gommander.FakeResult
+		retry    gommander.Retry
+		req      gommander.Request
+		attempts int
+		exitCode int
+	}{
+		{
+			"exit code",
+			[]gommander.FakeResult{{ExitCode: 75}, {ExitCode: 75}},
+			gommander.Retry{Attempts: 3, ExitCodes: []int{75}},
+			gommander.Request{},
+			3, 0,
+		},
+		{
+			"stderr",
+			[]gommander.FakeResult{locked},
+			gommander.Retry{Attempts: 3, Stderr: regexp.MustCompile(`get lock`)},
+			gommander.Request{},
+			2, 0,
+		},
+		{
+			"spooled stderr",
+			[]gommander.FakeResult{locked},
+			gommander.Retry{Attempts: 3, Stderr: regexp.MustCompile(`get lock`)},
+			gommander.Request{Spool: true, SpoolDir: t.TempDir()},
+			2, 0,
+		},
+		{
+			"pty stderr",
+			[]gommander.FakeResult{locked},
+			gommander.Retry{Attempts: 3, Stderr: regexp.MustCompile(`get lock`)},
+			gommander.Request{Pty: &gommander.Pty{}},
+			2, 0,
+		},
+		{
+			"error",
+			[]gommander.FakeResult{{Err: transient}},
+			gommander.Retry{Attempts: 2},
+			gommander.Request{},
+			2, 0,
+		},
+		{
+			"not retryable",
+			[]gommander.FakeResult{{ExitCode: 1}},
+			gommander.Retry{Attempts: 3, ExitCodes: []int{75}},
+			gommander.Request{},
+			1, 1,
+		},
+		{
+			"exhausted",
+			[]gommander.FakeResult{{ExitCode: 75}, {ExitCode: 75}},
+			gommander.Retry{Attempts: 2, ExitCodes: []int{75}},
+			gommander.Request{},
+			2, 75,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+
+			// Fail with each result in turn, then succeed
+			var mu sync.Mutex
+			failures := c.failures
+			fake := &gommander.FakeTransport{
+				Handler: func(*gommander.Node, string, []byte) gommander.FakeResult {
+					mu.Lock()
+					defer mu.Unlock()
+					if len(failures) == 0 {
+						return gommander.FakeResult{Stdout: "ok"}
+					}
+					res := failures[0]
+					failures = failures[1:]
+					return res
+				},
+			}
+			n := connect(t, gommander.NewFakeNode("node1", fake))[0]
+
+			retry := c.retry
+			retry.Backoff = time.Millisecond
+			req := c.req
+			req.Command = "cmd"
+			req.Retry = &retry
+
+			results, err := gommander.Collect(gommander.NodeList{n}.Execute(req))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := results.Responses[0]
+			r.Remove()
+			if r.Attempts != c.attempts || r.ExitCode != c.exitCode || r.Err != nil {
+				t.Errorf("expected %d attempts and exit %d, got %d attempts, exit %d, error %v",
+					c.attempts, c.exitCode, r.Attempts, r.ExitCode, r.Err)
+			}
+			if commands := fake.Commands(); len(commands) != c.attempts {
+				t.Errorf("expected %d commands, got %d", c.attempts, len(commands))
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+
+	p := &gommander.Retry{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if d := p.Delay(attempt + 1); d != expected {
+			t.Errorf("attempt %d: expected %s, got %s", attempt+1, expected, d)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Delay(1); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("expected up to 50%% jitter, got %s", d)
+		}
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+
+	job := gommander.NewJob(&gommander.Node{}, gommander.Request{})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		job.Cancel()
+	}()
+
+	// Cancelling stops waiting for the next attempt
+	start := time.Now()
+	if job.Sleep(time.Minute) {
+		t.Error("expected the wait to be interrupted")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("wait was not interrupted, took %s", d)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Secrets is a registry of secret values and patterns, which are masked
+// in the Responses, errors, log records and audit records of the Nodes it
+// is set on. The password of a Become is always masked.
+// Spooled output cannot be masked, so Requests to spool are refused.
+type Secrets struct {
+	mu       sync.RWMutex
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// ErrSpoolSecrets is returned when executing a Request which spools its
+// output on a Node with Secrets.
+var ErrSpoolSecrets = errors.New("Spooled output cannot be masked.")
+
+// Create a new Secrets registry.
+func NewSecrets() *Secrets {
+	return &Secrets{}
+}
+
+// Set the Secrets of each Node in the NodeList.
+func (l NodeList) SetSecrets(secrets *Secrets) {
+	for _, n := range l {
+		n.Secrets = secrets
+	}
+}
+
+// Add secret values. Empty values are ignored.
+func (s *Secrets) Add(values ...string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range values {
+		if len(v) > 0 {
+			s.values = append(s.values, v)
+		}
+	}
+
+	// Mask the longest values first, so no part of them is left
+	sort.SliceStable(s.values, func(i, j int) bool {
+		return len(s.values[i]) > len(s.values[j])
+	})
+}
+
+// Add patterns matching secrets.
+func (s *Secrets) AddPattern(patterns ...*regexp.Regexp) {
+	s.mu.Lock()
+	s.patterns = append(s.patterns, patterns...)
+	s.mu.Unlock()
+}
+
+// Mask the secrets in text.
+func (s *Secrets) Mask(text string) string {
+	return s.mask(text)
+}
+
+// Mask the secrets in text, along with the extra values given.
+// The Secrets may be nil.
+func (s *Secrets) mask(text string, extra ...string) string {
+
+	for _, v := range extra {
+		if len(v) > 0 {
+			text = strings.ReplaceAll(text, v, redactedValue)
+		}
+	}
+
+	if s == nil {
+		return text
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, v := range s.values {
+		text = strings.ReplaceAll(text, v, redactedValue)
+	}
+	for _, p := range s.patterns {
+		text = p.ReplaceAllString(text, redactedValue)
+	}
+
+	return text
+}
+
+// Mask the secrets in the message of an error. The error is returned as
+// is if its message holds none, and otherwise still unwraps to it.
+func (s *Secrets) maskError(err error, extra ...string) error {
+
+	if err == nil {
+		return nil
+	}
+
+	msg := s.mask(err.Error(), extra...)
+	if msg == err.Error() {
+		return err
+	}
+
+	return &maskedError{err: err, msg: msg}
+}
+
+type maskedError struct {
+	err error
+	msg string
+}
+
+func (e *maskedError) Error() string {
+	return e.msg
+}
+
+func (e *maskedError) Unwrap() error {
+	return e.err
+}
+
+// The become password for a Request on the Node, if any.
+func (n *Node) password(req *Request) string {
+	if req.Become != nil {
+		return req.Become.Password
+	}
+	if n.Become != nil {
+		return n.Become.Password
+	}
+	return ""
+}
+
+// Mask the secrets in the output, command and error of a Response.
+func (n *Node) maskResponse(req *Request, res *Response) {
+
+	password := n.password(req)
+	if n.Secrets == nil && len(password) == 0 {
+		return
+	}
+
+	for _, buf := range []**bytes.Buffer{&res.Stdout, &res.Stderr} {
+		if *buf == nil || (*buf).Len() == 0 {
+			continue
+		}
+		if masked := n.Secrets.mask((*buf).String(), password); masked != (*buf).String() {
+			*buf = bytes.NewBufferString(masked)
+		}
+	}
+
+	res.Command = n.Secrets.mask(res.Command, password)
+	res.Err = n.Secrets.maskError(res.Err, password)
+}
+
+// A slog.Handler which masks secrets in messages and attributes.
+type maskHandler struct {
+	handler slog.Handler
+	secrets *Secrets
+}
+
+func (h *maskHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *maskHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	masked := slog.NewRecord(r.Time, r.Level, h.secrets.Mask(r.Message), r.PC)
+
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(h.maskAttr(a))
+		return true
+	})
+
+	return h.handler.Handle(ctx, masked)
+}
+
+func (h *maskHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.maskAttr(a)
+	}
+
+	return &maskHandler{handler: h.handler.WithAttrs(masked), secrets: h.secrets}
+}
+
+func (h *maskHandler) WithGroup(name string) slog.Handler {
+	return &maskHandler{handler: h.handler.WithGroup(name), secrets: h.secrets}
+}
+
+func (h *maskHandler) maskAttr(a slog.Attr) slog.Attr {
+
+	v := a.Value.Resolve()
+
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.secrets.Mask(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		masked := make([]slog.Attr, len(group))
+		for i, g := range group {
+			masked[i] = h.maskAttr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(masked...)}
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, h.secrets.Mask(err.Error()))
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,167 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+)
+
+// SOCKS5 protocol values, from RFC 1928.
+const (
+	socksVersion     = 5
+	socksNoAuth      = 0
+	socksNoMethods   = 0xff
+	socksConnect     = 1
+	socksIPv4        = 1
+	socksDomain      = 3
+	socksIPv6        = 4
+	socksSucceeded   = 0
+	socksFailure     = 1
+	socksUnsupported = 7
+	socksBadAddress  = 8
+)
+
+// Start a SOCKS5 proxy on a local address, which dials connections through
+// the Node, like "ssh -D". Host names are resolved by the Node. Only the
+// CONNECT command is supported, without authentication, so the proxy
+// should listen on the loopback interface. The proxy is closed along with
+// the Node.
+func (n *Node) SOCKS5(addr string) (*Forward, error) {
+
+	if _, err := n.sshClient(); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.forward(listener, func(conn net.Conn) (net.Conn, error) {
+
+		target, err := socksHandshake(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		// Dial over the current connection, which is replaced on reconnecting
+		client, err := n.sshClient()
+		if err != nil {
+			socksReply(conn, socksFailure)
+			return nil, err
+		}
+
+		remote, err := client.Dial("tcp", target)
+		if err != nil {
+			socksReply(conn, socksFailure)
+			return nil, err
+		}
+
+		if err = socksReply(conn, socksSucceeded); err != nil {
+			remote.Close()
+			return nil, err
+		}
+
+		return remote, nil
+	}), nil
+}
+
+// Negotiate with a SOCKS5 client, returning the address it requests.
+func socksHandshake(conn net.Conn) (string, error) {
+
+	// Greeting: version, number of methods, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if header[0] != socksVersion {
+		return "", errors.New("Unsupported SOCKS version.")
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+
+	method := byte(socksNoMethods)
+	for _, m := range methods {
+		if m == socksNoAuth {
+			method = socksNoAuth
+		}
+	}
+
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return "", err
+	}
+	if method == socksNoMethods {
+		return "", errors.New("No acceptable SOCKS authentication method.")
+	}
+
+	// Request: version, command, reserved, address type
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", err
+	}
+	if request[1] != socksConnect {
+		socksReply(conn, socksUnsupported)
+		return "", errors.New("Unsupported SOCKS command.")
+	}
+
+	var host string
+
+	switch request[3] {
+	case socksIPv4, socksIPv6:
+		size := net.IPv4len
+		if request[3] == socksIPv6 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socksDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return "", err
+		}
+		name := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		socksReply(conn, socksBadAddress)
+		return "", errors.New("Unsupported SOCKS address type.")
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// Reply to a SOCKS5 request. The bound address is not meaningful through
+// an SSH connection, so it is always reported as 0.0.0.0:0.
+func socksReply(conn net.Conn, status byte) error {
+	_, err := conn.Write([]byte{socksVersion, status, 0, socksIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,173 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+// Run the handshake against a client sending request, returning the
+// address requested and what the server wrote back.
+func handshake(t *testing.T, request []byte) (string, []byte, error) {
+
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	type result struct {
+		target string
+		err    error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		target, err := gommander.SOCKSHandshake(server)
+		server.Close()
+		done <- result{target, err}
+	}()
+
+	go client.Write(request)
+
+	reply, _ := io.ReadAll(client)
+	r := <-done
+	return r.target, reply, r.err
+}
+
+func TestSOCKSHandshake(t *testing.T) {
+
+	greeting := []byte{5, 1, 0}
+
+	cases := []struct {
+		name    string
+		request []byte
+		target  string
+	}{
+		{"IPv4", []byte{5, 1, 0, 1, 10, 0, 0, 1, 0x0b, 0xbb}, "10.0.0.1:3003"},
+		{"domain", append(append([]byte{5, 1, 0, 3, 9}, "localhost"...), 0, 80), "localhost:80"},
+		{"IPv6", append(append([]byte{5, 1, 0, 4}, net.IPv6loopback...), 0x1f, 0x90), "[::1]:8080"},
+	}
+
+	for _, c := range cases {
+		target, reply, err := handshake(t, append(append([]byte{}, greeting...), c.request...))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if target != c.target {
+			t.Errorf("%s: target %q, expected %q", c.name, target, c.target)
+		}
+		if !bytes.Equal(reply, []byte{5, 0}) {
+			t.Errorf("%s: reply %v, expected the no authentication method", c.name, reply)
+		}
+	}
+}
+
+func TestSOCKSRefused(t *testing.T) {
+
+	cases := []struct {
+		name    string
+		request []byte
+		reply   []byte
+	}{
+		{"version", []byte{4, 1, 0}, nil},
+		{"authentication", []byte{5, 1, 2}, []byte{5, 0xff}},
+		{"bind", []byte{5, 1, 0, 5, 2, 0, 1}, []byte{5, 0, 5, gommander.SOCKSUnsupported, 0, 1, 0, 0, 0, 0, 0, 0}},
+		{"address type", []byte{5, 1, 0, 5, 1, 0, 9}, []byte{5, 0, 5, gommander.SOCKSBadAddress, 0, 1, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, c := range cases {
+		_, reply, err := handshake(t, c.request)
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+		if !bytes.Equal(reply, c.reply) {
+			t.Errorf("%s: reply %v, expected %v", c.name, reply, c.reply)
+		}
+	}
+}
+
+// Connect to the echo server through the proxy.
+func dialSOCKS(t *testing.T, proxy *gommander.Forward, echo net.Listener) net.Conn {
+
+	t.Helper()
+
+	conn, err := net.Dial("tcp", proxy.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Request the address of the echo server
+	addr := echo.Addr().(*net.TCPAddr)
+	request := append([]byte{5, 1, 0, 5, 1, 0, 1}, addr.IP.To4()...)
+	request = append(request, byte(addr.Port>>8), byte(addr.Port))
+	conn.Write(request)
+
+	reply := make([]byte, 12)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply[1] != 0 || reply[3] != 0 {
+		t.Fatalf("SOCKS request refused: %v", reply)
+	}
+
+	return conn
+}
+
+func TestSOCKS5(t *testing.T) {
+
+	echo := echoServer(t)
+	n := serve(t, gommandertest.Config{Forwarding: true}).Node("test", nil)
+	connect(t, n)
+
+	proxy, err := n.SOCKS5("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEcho(t, dialSOCKS(t, proxy, echo))
+
+	// The proxy is closed with the Node
+	n.Close()
+
+	if conn, err := net.Dial("tcp", proxy.Addr().String()); err == nil {
+		conn.Close()
+		t.Error("expected the proxy to be closed with the Node")
+	}
+}
+
+func TestSOCKS5Reconnect(t *testing.T) {
+
+	echo := echoServer(t)
+	server := serve(t, gommandertest.Config{Forwarding: true})
+	n := connect(t, server.Node("test", nil))[0]
+
+	proxy, err := n.SOCKS5("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconnect(t, server, n)
+
+	// The proxy dials over the new connection
+	expectEcho(t, dialSOCKS(t, proxy, echo))
+}
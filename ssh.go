@@ -0,0 +1,204 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHTransport executes commands over SSH, authenticating as the User of
+// the Node with its Auth methods. This is the default Transport.
+type SSHTransport struct {
+
+	// Guards the client, and the Pool it was acquired from
+	mu     sync.Mutex
+	client *ssh.Client
+
+	// Pool the client was acquired from, and its key there
+	pool *Pool
+	key  poolKey
+}
+
+// ErrNoHostKey is returned when connecting a Node which has no HostKey.
+var ErrNoHostKey = errors.New("No host key verification.")
+
+// Connect to the Node over SSH, sharing a connection from the Pool of
+// the Node if it has one.
+func (t *SSHTransport) Connect(n *Node) error {
+	return t.ConnectContext(context.Background(), n)
+}
+
+// Connect as Connect, tracing the dial and auth phases as children of
+// the Span in ctx.
+func (t *SSHTransport) ConnectContext(ctx context.Context, n *Node) error {
+
+	if n.Pool == nil {
+		client, _, err := dialSSH(ctx, n)
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.client = client
+		t.mu.Unlock()
+		return nil
+	}
+
+	key := keyOf(n)
+
+	client, err := n.Pool.acquire(key, func(client *ssh.Client, hostKey ssh.PublicKey) error {
+		if n.HostKey == nil {
+			return ErrNoHostKey
+		}
+		return n.HostKey(sshAddr(n), client.RemoteAddr(), hostKey)
+	}, func() (*ssh.Client, ssh.PublicKey, error) {
+		return dialSSH(ctx, n)
+	})
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.client = client
+	t.pool = n.Pool
+	t.key = key
+	t.mu.Unlock()
+	return nil
+}
+
+// Address of the SSH server of the Node.
+func sshAddr(n *Node) string {
+	return net.JoinHostPort(n.Host, strconv.Itoa(int(n.Port)))
+}
+
+// Dial an SSH connection for the Node, returning the host key it verified.
+func dialSSH(ctx context.Context, n *Node) (*ssh.Client, ssh.PublicKey, error) {
+
+	if n.HostKey == nil {
+		return nil, nil, ErrNoHostKey
+	}
+
+	var hostKey ssh.PublicKey
+
+	config := &ssh.ClientConfig{
+		User: n.User,
+		Auth: n.Auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return n.HostKey(hostname, remote, key)
+		},
+	}
+
+	addr := sshAddr(n)
+	n.log().Debug("ssh dial", "addr", addr, "user", n.User, "auth_methods", len(n.Auth))
+
+	_, span := n.span(ctx, "dial", Attribute{"addr", addr})
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", addr)
+	endSpan(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, span = n.span(ctx, "auth", Attribute{"user", n.User})
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	endSpan(span, err)
+	if err != nil {
+		conn.Close()
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			n.log().Warn("ssh auth failed", "addr", addr, "user", n.User, "error", err)
+		}
+		return nil, nil, err
+	}
+
+	n.log().Debug("ssh authenticated", "addr", addr, "user", n.User)
+	return ssh.NewClient(c, chans, reqs), hostKey, nil
+}
+
+// The SSH client, or nil if not connected.
+func (t *SSHTransport) Client() *ssh.Client {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.client
+}
+
+// Done returns a channel which is closed once the SSH connection is
+// closed, or lost.
+func (t *SSHTransport) Done() <-chan struct{} {
+
+	done := make(chan struct{})
+
+	if client := t.Client(); client != nil {
+		go func() {
+			client.Wait()
+			close(done)
+		}()
+	} else {
+		close(done)
+	}
+
+	return done
+}
+
+// Open an SSH session.
+func (t *SSHTransport) NewSession() (Session, error) {
+
+	client := t.Client()
+	if client == nil {
+		return nil, ErrNotConnected
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return &sshSession{session}, nil
+}
+
+// Close the SSH connection, or return it to the Pool.
+func (t *SSHTransport) Close() error {
+
+	t.mu.Lock()
+	client, pool, key := t.client, t.pool, t.key
+	t.client, t.pool = nil, nil
+	t.mu.Unlock()
+
+	if client == nil {
+		return nil
+	}
+
+	if pool != nil {
+		pool.release(key, client)
+		return nil
+	}
+
+	return client.Close()
+}
+
+type sshSession struct {
+	*ssh.Session
+}
+
+func (s *sshSession) SetOutput(stdout, stderr io.Writer) {
+	s.Stdout = stdout
+	s.Stderr = stderr
+}
@@ -0,0 +1,33 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+func TestNoHostKey(t *testing.T) {
+
+	n := serve(t, gommandertest.Config{}).Node("test", nil)
+	n.HostKey = nil
+
+	if err := n.Connect(); err != gommander.ErrNoHostKey {
+		n.Close()
+		t.Fatalf("expected ErrNoHostKey, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"errors"
+)
+
+// ErrNotConnected is returned when using a Node which is not connected.
+var ErrNotConnected = errors.New("Not connected.")
+
+// ErrConnected is returned when connecting a Node which is connected.
+var ErrConnected = errors.New("Already connected.")
+
+// State is the lifecycle state of a Node.
+type State int
+
+const (
+	// Not yet connected, or the connection was lost
+	Disconnected State = iota
+
+	// Connecting
+	Connecting
+
+	// Connected, and waiting for Requests
+	Idle
+
+	// Connected, and executing a Request
+	Busy
+
+	// Closed by Close
+	Closed
+)
+
+func (s State) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connecting:
+		return "connecting"
+	case Idle:
+		return "idle"
+	case Busy:
+		return "busy"
+	case Closed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Hooks are functions called on events of a Node. Any may be nil.
+// They are called synchronously, from the goroutine causing the event,
+// so should return quickly.
+type Hooks struct {
+
+	// Called when the state of the Node changes
+	OnStateChange func(n *Node, from State, to State)
+
+	// Called once the Node is connected
+	OnConnect func(n *Node)
+
+	// Called when the Node is closed, or its connection is lost
+	OnDisconnect func(n *Node)
+
+	// Called before each attempt to execute a Request
+	OnRequestStart func(n *Node, req *Request)
+
+	// Called with each Response, before it is sent to the Request
+	OnResponse func(n *Node, res Response)
+
+	// Called with errors connecting, and Response errors
+	OnError func(n *Node, err error)
+}
+
+// State of the Node.
+func (n *Node) State() State {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.state
+}
+
+// Subscribe to events of the Node.
+func (n *Node) Subscribe(h Hooks) {
+	n.mu.Lock()
+	n.hooks = append(n.hooks, h)
+	n.mu.Unlock()
+}
+
+// Subscribe to events of each Node in the NodeList.
+func (l NodeList) Subscribe(h Hooks) {
+	for _, n := range l {
+		n.Subscribe(h)
+	}
+}
+
+// Change the state of the Node, notifying subscribers.
+func (n *Node) setState(to State) {
+
+	n.mu.Lock()
+	from := n.state
+	n.state = to
+	hooks := n.hooks
+	n.mu.Unlock()
+
+	if from == to {
+		return
+	}
+
+	for _, h := range hooks {
+		if h.OnStateChange != nil {
+			h.OnStateChange(n, from, to)
+		}
+	}
+
+	for _, h := range hooks {
+		switch {
+		case to == Idle && from == Connecting:
+			if h.OnConnect != nil {
+				h.OnConnect(n)
+			}
+		case (to == Closed || to == Disconnected) && (from == Idle || from == Busy):
+			if h.OnDisconnect != nil {
+				h.OnDisconnect(n)
+			}
+		}
+	}
+}
+
+// The hooks subscribed to the Node.
+func (n *Node) subscribers() []Hooks {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.hooks
+}
+
+func (n *Node) emitRequestStart(req *Request) {
+	for _, h := range n.subscribers() {
+		if h.OnRequestStart != nil {
+			h.OnRequestStart(n, req)
+		}
+	}
+}
+
+func (n *Node) emitResponse(res Response) {
+	for _, h := range n.subscribers() {
+		if h.OnResponse != nil {
+			h.OnResponse(n, res)
+		}
+	}
+	if res.Err != nil {
+		n.emitError(res.Err)
+	}
+}
+
+func (n *Node) emitError(err error) {
+	for _, h := range n.subscribers() {
+		if h.OnError != nil {
+			h.OnError(n, err)
+		}
+	}
+}
+
+// Watch for the loss of a connection, for Transports which report it.
+// The generation identifies the connection, so a lost connection is not
+// confused with a newer one.
+func (n *Node) watchConnection(generation int) {
+
+	t, ok := n.Transport.(interface{ Done() <-chan struct{} })
+	if !ok {
+		return
+	}
+
+	done := t.Done()
+
+	go func() {
+		<-done
+
+		n.mu.Lock()
+		current := n.generation == generation && (n.state == Idle || n.state == Busy)
+		n.mu.Unlock()
+
+		if current {
+			n.setState(Disconnected)
+		}
+	}()
+}
@@ -0,0 +1,173 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+func TestHooks(t *testing.T) {
+
+	server := serve(t, gommandertest.Config{
+		Handler: gommandertest.Script(map[string]gommandertest.Result{"true": {}}),
+	})
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+
+	n := server.Node("test", nil)
+	n.Subscribe(gommander.Hooks{
+		OnStateChange: func(n *gommander.Node, from, to gommander.State) {
+			record(from.String() + ">" + to.String())
+		},
+		OnConnect:      func(*gommander.Node) { record("connect") },
+		OnDisconnect:   func(*gommander.Node) { record("disconnect") },
+		OnRequestStart: func(_ *gommander.Node, req *gommander.Request) { record("start " + req.Command) },
+		OnResponse:     func(_ *gommander.Node, res gommander.Response) { record("response") },
+		OnError:        func(*gommander.Node, error) { record("error") },
+	})
+
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Connect(); err != gommander.ErrConnected {
+		t.Errorf("expected ErrConnected, got %v", err)
+	}
+
+	if _, err := gommander.Collect(gommander.NodeList{n}.Run("true")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Losing the connection disconnects the Node
+	server.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for n.State() != gommander.Disconnected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the Node to be disconnected, is %s", n.State())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	n.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := "disconnected>connecting, connecting>idle, connect, " +
+		"idle>busy, start true, busy>idle, response, " +
+		"idle>disconnected, disconnect, disconnected>closed"
+
+	if got := strings.Join(events, ", "); got != expected {
+		t.Errorf("unexpected events:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestExecuteClose(t *testing.T) {
+
+	n := serve(t, gommandertest.Config{
+		Handler: gommandertest.Script(map[string]gommandertest.Result{
+			"sleep": {Delay: 10 * time.Millisecond},
+		}),
+	}).Node("test", nil)
+	connect(t, n)
+
+	var (
+		mu   sync.Mutex
+		jobs []*gommander.Job
+		wg   sync.WaitGroup
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job, err := n.Execute(gommander.Request{
+				Command: "sleep",
+				Respond: func(gommander.Response) error { return nil },
+			})
+			if err != nil {
+				if err != gommander.ErrNotConnected {
+					t.Error(err)
+				}
+				return
+			}
+			mu.Lock()
+			jobs = append(jobs, job)
+			mu.Unlock()
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	n.Close()
+	wg.Wait()
+
+	// Every Job accepted before Close completes
+	for _, job := range jobs {
+		select {
+		case <-job.Done():
+		case <-time.After(10 * time.Second):
+			t.Fatal("job never completed")
+		}
+	}
+}
+
+func TestConnectRetry(t *testing.T) {
+
+	server := serve(t, gommandertest.Config{})
+	down := server.Node("test", nil)
+	down.Port = 1
+
+	// A failed connection leaves the rest connected
+	nodes := gommander.NodeList{gommander.NewFakeNode("node1", &gommander.FakeTransport{}), down}
+	if err := nodes.Connect(); err == nil {
+		t.Fatal("expected the connection to fail")
+	}
+	t.Cleanup(func() { nodes.Close() })
+
+	// Retrying skips those already connected
+	down.Port = server.Port()
+	if err := nodes.Connect(); err != nil {
+		t.Fatalf("expected the retry to connect the rest, got %v", err)
+	}
+	for _, n := range nodes {
+		if state := n.State(); state != gommander.Idle {
+			t.Errorf("%s: expected to be connected, is %s", n.Name(), state)
+		}
+	}
+
+	// Closing skips those not connected
+	nodes[0].Close()
+	if err := nodes.Close(); err != nil {
+		t.Errorf("expected the rest to close, got %v", err)
+	}
+	if state := down.State(); state != gommander.Closed {
+		t.Errorf("expected the Node to be closed, is %s", state)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"context"
+)
+
+// Tracer starts Spans, timing NodeList operations, their execution on
+// each Node, and the phases of that execution: connect, dial, auth,
+// attempt, session, command and transfer. Spans are named with the
+// "gommander." prefix.
+type Tracer interface {
+
+	// Start a Span, as a child of the Span in ctx if any, returning a
+	// context holding the new Span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span times a single operation.
+type Span interface {
+
+	// Add attributes to the Span.
+	SetAttributes(attrs ...Attribute)
+
+	// Record an error which caused the operation to fail.
+	RecordError(err error)
+
+	// End the Span.
+	End()
+}
+
+// Attribute of a Span. The Value is a string, bool, int, int64,
+// float64 or time.Duration.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Set the Tracer of each Node in the NodeList.
+func (l NodeList) SetTracer(tracer Tracer) {
+	for _, n := range l {
+		n.Tracer = tracer
+	}
+}
+
+// Start a Span for an operation on the NodeList, with the Tracer of its
+// first Node which has one.
+func (l NodeList) span(op string) (context.Context, Span) {
+
+	ctx := context.Background()
+
+	for _, n := range l {
+		if n.Tracer != nil {
+			return n.Tracer.Start(ctx, "gommander."+op, Attribute{"nodes", len(l)})
+		}
+	}
+
+	return ctx, noopSpan{}
+}
+
+// Start a Span on the Node, as a child of the Span in ctx if any.
+func (n *Node) span(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if n.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	attrs = append([]Attribute{{"node", n.Name()}}, attrs...)
+	return n.Tracer.Start(ctx, "gommander."+name, attrs...)
+}
+
+// End the Span for the execution of a Request on a Node.
+func endResponse(span Span, res Response) {
+
+	span.SetAttributes(
+		Attribute{"exit_code", res.ExitCode},
+		Attribute{"attempts", res.Attempts},
+		Attribute{"duration", res.Duration},
+		Attribute{"bytes_in", res.BytesIn},
+		Attribute{"bytes_out", res.BytesOut},
+	)
+
+	if res.Err != nil {
+		span.RecordError(res.Err)
+	}
+
+	span.End()
+}
+
+// End a Span, recording the error if any.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// Span used when there is no Tracer.
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+func (noopSpan) RecordError(err error)            {}
+func (noopSpan) End()                             {}
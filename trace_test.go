@@ -0,0 +1,137 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aerospike/gommander"
+	"github.com/aerospike/gommander/gommandertest"
+)
+
+// A Tracer recording the Spans it starts, with their parents.
+type recorder struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+type span struct {
+	name   string
+	parent *span
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+type spanKey struct{}
+
+func (r *recorder) Start(ctx context.Context, name string, attrs ...gommander.Attribute) (context.Context, gommander.Span) {
+
+	s := &span{name: name, attrs: map[string]interface{}{}}
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.parent = parent
+	}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, s), &recorded{r, s}
+}
+
+type recorded struct {
+	r *recorder
+	s *span
+}
+
+func (s *recorded) SetAttributes(attrs ...gommander.Attribute) {
+	s.r.mu.Lock()
+	for _, a := range attrs {
+		s.s.attrs[a.Key] = a.Value
+	}
+	s.r.mu.Unlock()
+}
+
+func (s *recorded) RecordError(err error) {
+	s.r.mu.Lock()
+	s.s.err = err
+	s.r.mu.Unlock()
+}
+
+func (s *recorded) End() {
+	s.r.mu.Lock()
+	s.s.ended = true
+	s.r.mu.Unlock()
+}
+
+// The path of span names from the root.
+func (s *span) path() string {
+	if s.parent == nil {
+		return s.name
+	}
+	return s.parent.path() + "/" + s.name
+}
+
+func TestTrace(t *testing.T) {
+
+	server := serve(t, gommandertest.Config{
+		Handler: gommandertest.Script(map[string]gommandertest.Result{"false": {ExitCode: 1}}),
+	})
+
+	tracer := &recorder{}
+
+	nodes := gommander.NodeList{server.Node("test", nil)}
+	nodes.SetTracer(tracer)
+
+	connect(t, nodes...)
+
+	if _, err := gommander.Collect(nodes.Run("false")); err != nil {
+		t.Fatal(err)
+	}
+
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+
+	paths := map[string]*span{}
+	for _, s := range tracer.spans {
+		paths[s.path()] = s
+		if !s.ended {
+			t.Errorf("span %s not ended", s.path())
+		}
+	}
+
+	for _, path := range []string{
+		"gommander.Connect/gommander.connect/gommander.dial",
+		"gommander.Connect/gommander.connect/gommander.auth",
+		"gommander.Run/gommander.node/gommander.attempt/gommander.session",
+		"gommander.Run/gommander.node/gommander.attempt/gommander.command",
+	} {
+		if paths[path] == nil {
+			t.Errorf("missing span %s", path)
+		}
+	}
+
+	// A command which exits is not an error, but records its status
+	if s := paths["gommander.Run/gommander.node/gommander.attempt/gommander.command"]; s != nil {
+		if s.err != nil || s.attrs["exit_code"] != 1 {
+			t.Errorf("expected the command span to record exit code 1, got %v, %v", s.attrs, s.err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2013-2014 Aerospike, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gommander
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Transport connects a Node to the system its commands are executed on.
+// Each Node needs its own Transport value, unless the implementation
+// states otherwise.
+type Transport interface {
+
+	// Connect for the given Node.
+	Connect(n *Node) error
+
+	// Open a Session to execute a single command.
+	NewSession() (Session, error)
+
+	// Close the connection.
+	Close() error
+}
+
+// Session executes a single command, following the semantics of
+// ssh.Session. Wait returns an error implementing ExitStatus() int and
+// Signal() string, such as *ssh.ExitError or one from NewExitError,
+// if the command exits with a non-zero status.
+type Session interface {
+
+	// Set an environment variable for the command. An error means the
+	// variable was refused.
+	Setenv(name, value string) error
+
+	// Allocate a pseudo-terminal for the command.
+	RequestPty(term string, height, width int, modes ssh.TerminalModes) error
+
+	// Change the size of the pseudo-terminal.
+	WindowChange(height, width int) error
+
+	// Send a signal to the running command.
+	Signal(sig ssh.Signal) error
+
+	// A pipe to the stdin of the command.
+	StdinPipe() (io.WriteCloser, error)
+
+	// Set where the stdout and stderr of the command are written.
+	// A nil Writer discards the stream.
+	SetOutput(stdout, stderr io.Writer)
+
+	// Start the command.
+	Start(command string) error
+
+	// Wait for the command to exit.
+	Wait() error
+
+	// Close the session, abandoning the command if still running.
+	Close() error
+}
+
+// A Transport which traces the phases of its connection, as children
+// of the Span in ctx.
+type contextConnector interface {
+	ConnectContext(ctx context.Context, n *Node) error
+}
+
+// A Session which wraps the command it is started with, such as to run it
+// in a container.
+type commandWrapper interface {
+	wrap(command string) string
+}
+
+// The exit status of a command, as reported by Session.Wait.
+type exitStatus interface {
+	ExitStatus() int
+	Signal() string
+}
+
+// Create an error for Session.Wait, for a command which exited with a
+// non-zero status, or was terminated by a signal.
+func NewExitError(status int, signal string) error {
+	return &exitError{status: status, signal: signal}
+}
+
+type exitError struct {
+	status int
+	signal string
+}
+
+func (e *exitError) ExitStatus() int {
+	return e.status
+}
+
+func (e *exitError) Signal() string {
+	return e.signal
+}
+
+func (e *exitError) Error() string {
+	if len(e.signal) > 0 {
+		return fmt.Sprintf("Exited with signal %s.", e.signal)
+	}
+	return fmt.Sprintf("Exited with status %d.", e.status)
+}